@@ -0,0 +1,6 @@
+//go:build !zsetdebug
+
+package zset
+
+// debugChecks 默认关闭，内部结构出现不可能的状态时尽量保持结构可用而不 panic。
+const debugChecks = false
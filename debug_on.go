@@ -0,0 +1,6 @@
+//go:build zsetdebug
+
+package zset
+
+// debugChecks 在使用 -tags zsetdebug 构建时开启，内部结构出现不可能的状态时直接 panic，便于尽早定位数据损坏。
+const debugChecks = true
@@ -0,0 +1,278 @@
+package zset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strings"
+)
+
+// 序列化格式（所有整数均为大端序）：
+//
+//	count  uint64        元素数量
+//	重复 count 次：
+//	score  uint64        分数的 IEEE 754 位模式
+//	length uint32        元素编码后的字节长度
+//	ele    [length]byte  元素编码：字符串为其内容，其他类型为 JSON
+
+// encodeMember 将元素编码为字节：字符串元素直接使用其内容，其他类型使用 JSON 编码。
+// ele: 要编码的元素。
+// 返回编码后的字节和遇到的错误。
+func encodeMember[M comparable](ele M) ([]byte, error) {
+	if s, ok := any(ele).(string); ok {
+		return []byte(s), nil
+	}
+	return json.Marshal(ele)
+}
+
+// decodeMember 将 encodeMember 编码的字节解码为元素。
+// data: 编码后的字节。
+// 返回解码得到的元素和遇到的错误。
+func decodeMember[M comparable](data []byte) (M, error) {
+	var ele M
+	if p, ok := any(&ele).(*string); ok {
+		*p = string(data)
+		return ele, nil
+	}
+	err := json.Unmarshal(data, &ele)
+	return ele, err
+}
+
+// writeEntry 将一个元素及其分数按序列化格式写入 w。
+// w: 写入目标。
+// ele: 元素值。
+// score: 元素的分数。
+// 返回写入的字节数和遇到的错误。
+func writeEntry[M comparable](w io.Writer, ele M, score float64) (int64, error) {
+	data, err := encodeMember(ele)
+	if err != nil {
+		return 0, err
+	}
+
+	var head [12]byte
+	binary.BigEndian.PutUint64(head[0:8], math.Float64bits(score))
+	binary.BigEndian.PutUint32(head[8:12], uint32(len(data)))
+
+	n, err := w.Write(head[:])
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+
+	n, err = w.Write(data)
+	written += int64(n)
+	return written, err
+}
+
+// WriteTo 将 ZSet 的全部元素按升序序列化写入 w，实现 io.WriterTo 接口。
+// w: 写入目标。
+// 返回写入的字节数和遇到的错误。
+func (z *ZSet[M]) WriteTo(w io.Writer) (int64, error) {
+	return z.WriteRangeTo(w, 0, -1, false)
+}
+
+// WriteRangeTo 将指定排名区间内的元素按与 WriteTo 相同的格式序列化写入 w。
+// w: 写入目标。
+// start: 起始排名（从 0 开始），负数表示从末尾倒数。
+// stop: 结束排名（包含），负数表示从末尾倒数。
+// reverse: 是否按降序排名。
+// 返回写入的字节数和遇到的错误；区间为空时只写入数量 0。
+func (z *ZSet[M]) WriteRangeTo(w io.Writer, start, stop int64, reverse bool) (int64, error) {
+	z.rlock()
+	defer z.runlock()
+
+	start, stop, ok := normalizeRange(start, stop, z.zsl.length)
+
+	var count uint64 = 0
+	if ok {
+		count = uint64(stop - start + 1)
+	}
+
+	// 写入元素数量
+	var head [8]byte
+	binary.BigEndian.PutUint64(head[:], count)
+	n, err := w.Write(head[:])
+	written := int64(n)
+	if err != nil || count == 0 {
+		return written, err
+	}
+
+	// 定位起始节点
+	var x *skiplistNode[M]
+	if reverse {
+		x = z.zsl.getElementByRank(z.zsl.length - uint64(start))
+	} else {
+		x = z.zsl.getElementByRank(uint64(start) + 1)
+	}
+
+	// 依次写入区间内的元素
+	for i := uint64(0); i < count && x != nil; i++ {
+		m, err := writeEntry(w, x.ele, x.score)
+		written += m
+		if err != nil {
+			return written, err
+		}
+
+		if reverse {
+			x = x.backward
+		} else {
+			x = x.level[0].forward
+		}
+	}
+
+	return written, nil
+}
+
+// ErrNoComparator 表示零值 ZSet 的元素类型没有默认排序规则，需先通过 NewZSetFunc 创建。
+var ErrNoComparator = errors.New("zset: no default comparator for member type, create the set with NewZSetFunc")
+
+// jsonEntry 是序列化时单个元素的表示。
+type jsonEntry[M comparable] struct {
+	Member M         `json:"member"`
+	Score  jsonScore `json:"score"`
+}
+
+// jsonScore 是 JSON 中的分数：有限值编码为数字，±Inf 与 Redis 一致编码为字符串 "inf" / "-inf"。
+type jsonScore float64
+
+// MarshalJSON 实现 json.Marshaler 接口。
+func (s jsonScore) MarshalJSON() ([]byte, error) {
+	switch {
+	case math.IsInf(float64(s), 1):
+		return []byte(`"inf"`), nil
+	case math.IsInf(float64(s), -1):
+		return []byte(`"-inf"`), nil
+	}
+	return json.Marshal(float64(s))
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler 接口，接受数字以及字符串 "inf"、"+inf"、"-inf"（不区分大小写）。
+func (s *jsonScore) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || data[0] != '"' {
+		return json.Unmarshal(data, (*float64)(s))
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	switch strings.ToLower(str) {
+	case "inf", "+inf":
+		*s = jsonScore(math.Inf(1))
+	case "-inf":
+		*s = jsonScore(math.Inf(-1))
+	default:
+		return ErrInvalidScore
+	}
+	return nil
+}
+
+// MarshalJSON 将 ZSet 序列化为按升序排列的 [{"member": ..., "score": ...}] 数组，实现 json.Marshaler 接口。
+// 分数为 ±Inf 时编码为字符串 "inf" / "-inf"，UnmarshalJSON 可以还原。
+func (z *ZSet[M]) MarshalJSON() ([]byte, error) {
+	z.rlock()
+	defer z.runlock()
+
+	entries := make([]jsonEntry[M], 0, z.zsl.length)
+	for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		entries = append(entries, jsonEntry[M]{Member: x.ele, Score: jsonScore(x.score)})
+	}
+	return json.Marshal(entries)
+}
+
+// UnmarshalJSON 从 MarshalJSON 产生的数组重建 ZSet，实现 json.Unmarshaler 接口。
+// 原有元素会被丢弃，排序规则和最大层级保持不变；重复的元素以最后一次出现的分数为准。
+// 零值 ZSet 仅当元素类型为 string 时可以直接反序列化，否则返回 ErrNoComparator。
+// 解码失败时集合保持不变。
+func (z *ZSet[M]) UnmarshalJSON(data []byte) error {
+	var entries []jsonEntry[M]
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	z.lock()
+	defer z.unlock()
+
+	if err := z.reset(); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		z.add(e.Member, float64(e.Score))
+	}
+	return nil
+}
+
+// ErrInvalidBinary 表示二进制数据被截断或格式不正确。
+var ErrInvalidBinary = errors.New("zset: invalid binary data")
+
+// MarshalBinary 按与 WriteTo 相同的格式将 ZSet 序列化为字节，实现 encoding.BinaryMarshaler 接口。
+func (z *ZSet[M]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := z.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary 从 MarshalBinary 或 WriteTo 产生的字节重建 ZSet，实现 encoding.BinaryUnmarshaler 接口。
+// 原有元素会被丢弃，排序规则和最大层级保持不变。
+// 数据被截断、长度不符、分数为 NaN 或元素无法解码时返回 ErrInvalidBinary，且集合保持不变。
+func (z *ZSet[M]) UnmarshalBinary(data []byte) error {
+	if len(data) < 8 {
+		return ErrInvalidBinary
+	}
+	count := binary.BigEndian.Uint64(data[0:8])
+	data = data[8:]
+
+	// 先完整解析，确保出错时不修改集合
+	var entries []jsonEntry[M]
+	for i := uint64(0); i < count; i++ {
+		if len(data) < 12 {
+			return ErrInvalidBinary
+		}
+		score := math.Float64frombits(binary.BigEndian.Uint64(data[0:8]))
+		if math.IsNaN(score) {
+			return ErrInvalidBinary
+		}
+		length := uint64(binary.BigEndian.Uint32(data[8:12]))
+		data = data[12:]
+		if uint64(len(data)) < length {
+			return ErrInvalidBinary
+		}
+
+		ele, err := decodeMember[M](data[:length])
+		if err != nil {
+			return ErrInvalidBinary
+		}
+		data = data[length:]
+		entries = append(entries, jsonEntry[M]{Member: ele, Score: jsonScore(score)})
+	}
+	if len(data) != 0 {
+		return ErrInvalidBinary
+	}
+
+	z.lock()
+	defer z.unlock()
+
+	if err := z.reset(); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		z.add(e.Member, float64(e.Score))
+	}
+	return nil
+}
+
+// GobEncode 实现 gob.GobEncoder 接口，编码格式与 MarshalBinary 相同。
+func (z *ZSet[M]) GobEncode() ([]byte, error) {
+	return z.MarshalBinary()
+}
+
+// GobDecode 实现 gob.GobDecoder 接口，从 GobEncode 产生的字节重建 ZSet。
+// 解码到零值 ZSet 时使用默认的排序规则，元素类型没有默认排序规则时返回 ErrNoComparator。
+func (z *ZSet[M]) GobDecode(data []byte) error {
+	return z.UnmarshalBinary(data)
+}
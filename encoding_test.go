@@ -0,0 +1,342 @@
+package zset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// decodeEntries 按序列化格式解析 WriteTo 写出的数据，仅供测试使用。
+func decodeEntries(t *testing.T, data []byte) []struct {
+	Member string
+	Score  float64
+} {
+	var result []struct {
+		Member string
+		Score  float64
+	}
+
+	count := binary.BigEndian.Uint64(data[0:8])
+	data = data[8:]
+	for i := uint64(0); i < count; i++ {
+		score := math.Float64frombits(binary.BigEndian.Uint64(data[0:8]))
+		length := binary.BigEndian.Uint32(data[8:12])
+		ele := string(data[12 : 12+length])
+		data = data[12+length:]
+		result = append(result, struct {
+			Member string
+			Score  float64
+		}{ele, score})
+	}
+	assert.Empty(t, data)
+	return result
+}
+
+type failingWriter struct {
+	remaining int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.remaining {
+		n := w.remaining
+		w.remaining = 0
+		return n, errors.New("write failed")
+	}
+	w.remaining -= len(p)
+	return len(p), nil
+}
+
+func TestZSet_WriteRangeTo(t *testing.T) {
+	type entry = struct {
+		Member string
+		Score  float64
+	}
+
+	setup := func() *ZSet[string] {
+		z := NewZSet()
+		z.Add("a", 1)
+		z.Add("b", 2)
+		z.Add("c", 3)
+		z.Add("d", 4)
+		return z
+	}
+
+	tests := []struct {
+		name     string
+		setup    func() *ZSet[string]
+		start    int64
+		stop     int64
+		reverse  bool
+		expected []entry
+	}{
+		{name: "empty set", setup: NewZSet, start: 0, stop: -1, expected: nil},
+		{name: "whole set", setup: setup, start: 0, stop: -1, expected: []entry{{"a", 1}, {"b", 2}, {"c", 3}, {"d", 4}}},
+		{name: "middle window", setup: setup, start: 1, stop: 2, expected: []entry{{"b", 2}, {"c", 3}}},
+		{name: "negative indices", setup: setup, start: -2, stop: -1, expected: []entry{{"c", 3}, {"d", 4}}},
+		{name: "reverse window", setup: setup, start: 0, stop: 1, reverse: true, expected: []entry{{"d", 4}, {"c", 3}}},
+		{name: "stop clamped", setup: setup, start: 2, stop: 100, expected: []entry{{"c", 3}, {"d", 4}}},
+		{name: "start after stop", setup: setup, start: 3, stop: 1, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := tt.setup()
+			var buf bytes.Buffer
+			n, err := z.WriteRangeTo(&buf, tt.start, tt.stop, tt.reverse)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(buf.Len()), n)
+			assert.Equal(t, tt.expected, decodeEntries(t, buf.Bytes()))
+		})
+	}
+
+	t.Run("write error", func(t *testing.T) {
+		z := setup()
+		w := &failingWriter{remaining: 10}
+		n, err := z.WriteRangeTo(w, 0, -1, false)
+		assert.Error(t, err)
+		assert.Equal(t, int64(10), n)
+	})
+}
+
+func TestZSet_WriteTo(t *testing.T) {
+	z := NewZSet()
+	z.Add("b", 2)
+	z.Add("a", 1)
+
+	var full, window bytes.Buffer
+	n, err := z.WriteTo(&full)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(full.Len()), n)
+
+	_, err = z.WriteRangeTo(&window, 0, -1, false)
+	assert.NoError(t, err)
+	assert.Equal(t, window.Bytes(), full.Bytes())
+	assert.Len(t, decodeEntries(t, full.Bytes()), 2)
+}
+
+func TestZSet_JSON(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("c", 3)
+		z.Add("a", 1)
+		z.Add("b", 1)
+		z.Add("d", -2.5)
+
+		data, err := json.Marshal(z)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `[{"member":"d","score":-2.5},{"member":"a","score":1},{"member":"b","score":1},{"member":"c","score":3}]`, string(data))
+
+		restored := NewZSet()
+		restored.Add("stale", 100)
+		assert.NoError(t, json.Unmarshal(data, restored))
+
+		assert.Equal(t, z.Len(), restored.Len())
+		assert.Equal(t, z.dict, restored.dict)
+		for ele := range z.dict {
+			assert.Equal(t, z.Rank(ele, false), restored.Rank(ele, false))
+		}
+		_, exists := restored.Score("stale")
+		assert.False(t, exists)
+	})
+
+	t.Run("empty set", func(t *testing.T) {
+		data, err := json.Marshal(NewZSet())
+		assert.NoError(t, err)
+		assert.Equal(t, "[]", string(data))
+
+		restored := NewZSet()
+		assert.NoError(t, json.Unmarshal(data, restored))
+		assert.Equal(t, uint64(0), restored.Len())
+	})
+
+	t.Run("zero value string set", func(t *testing.T) {
+		var z ZSet[string]
+		assert.NoError(t, json.Unmarshal([]byte(`[{"member":"b","score":1},{"member":"a","score":1}]`), &z))
+		assert.Equal(t, int64(0), z.Rank("a", false))
+		assert.Equal(t, int64(1), z.Rank("b", false))
+	})
+
+	t.Run("zero value without comparator", func(t *testing.T) {
+		var z ZSet[int]
+		err := json.Unmarshal([]byte(`[{"member":1,"score":1}]`), &z)
+		assert.ErrorIs(t, err, ErrNoComparator)
+	})
+
+	t.Run("generic members keep comparator", func(t *testing.T) {
+		less := func(a, b int) bool { return a > b }
+		z := NewZSetFunc(less)
+		z.Add(1, 0)
+		z.Add(2, 0)
+		data, err := json.Marshal(z)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `[{"member":2,"score":0},{"member":1,"score":0}]`, string(data))
+
+		restored := NewZSetFunc(less)
+		assert.NoError(t, json.Unmarshal(data, restored))
+		assert.Equal(t, int64(0), restored.Rank(2, false))
+	})
+
+	t.Run("infinite scores", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("a", 1)
+		z.Add("low", math.Inf(-1))
+		z.Add("high", math.Inf(1))
+		z.IncrBy("a", math.MaxFloat64)
+		z.IncrBy("a", math.MaxFloat64)
+
+		data, err := json.Marshal(z)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `[{"member":"low","score":"-inf"},{"member":"a","score":"inf"},{"member":"high","score":"inf"}]`, string(data))
+
+		restored := NewZSet()
+		assert.NoError(t, json.Unmarshal(data, restored))
+		assert.Equal(t, z.dict, restored.dict)
+		assert.Equal(t, z.Members(false), restored.Members(false))
+		assert.NoError(t, restored.Validate())
+
+		assert.NoError(t, json.Unmarshal([]byte(`[{"member":"x","score":"+INF"},{"member":"y","score":"-Inf"}]`), restored))
+		score, _ := restored.Score("x")
+		assert.True(t, math.IsInf(score, 1))
+		score, _ = restored.Score("y")
+		assert.True(t, math.IsInf(score, -1))
+	})
+
+	t.Run("invalid input leaves set unchanged", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("a", 1)
+		assert.Error(t, json.Unmarshal([]byte(`{"member":"a"}`), z))
+		assert.ErrorIs(t, json.Unmarshal([]byte(`[{"member":"b","score":"nan"}]`), z), ErrInvalidScore)
+		assert.Error(t, json.Unmarshal([]byte(`[{"member":"b","score":true}]`), z))
+		assert.Equal(t, uint64(1), z.Len())
+	})
+}
+
+func TestZSet_Binary(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("c", 3)
+		z.Add("a", 1)
+		z.Add("b", 1)
+		z.Add("", math.Inf(-1))
+		z.Add("长元素", 2.5)
+
+		data, err := z.MarshalBinary()
+		assert.NoError(t, err)
+
+		restored := NewZSet()
+		restored.Add("stale", 1)
+		assert.NoError(t, restored.UnmarshalBinary(data))
+		assert.Equal(t, z.dict, restored.dict)
+		assert.Equal(t, z.RangeByRank(0, -1, false), restored.RangeByRank(0, -1, false))
+	})
+
+	t.Run("empty set", func(t *testing.T) {
+		data, err := NewZSet().MarshalBinary()
+		assert.NoError(t, err)
+		assert.Len(t, data, 8)
+
+		restored := NewZSet()
+		restored.Add("stale", 1)
+		assert.NoError(t, restored.UnmarshalBinary(data))
+		assert.Equal(t, uint64(0), restored.Len())
+	})
+
+	t.Run("generic members", func(t *testing.T) {
+		z := NewZSetFunc(func(a, b int) bool { return a < b })
+		z.Add(3, 1)
+		z.Add(1, 1)
+		data, err := z.MarshalBinary()
+		assert.NoError(t, err)
+
+		restored := NewZSetFunc(func(a, b int) bool { return a < b })
+		assert.NoError(t, restored.UnmarshalBinary(data))
+		assert.Equal(t, int64(0), restored.Rank(1, false))
+		assert.Equal(t, int64(1), restored.Rank(3, false))
+	})
+
+	t.Run("corrupt input", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("alpha", 1)
+		z.Add("beta", 2)
+		data, err := z.MarshalBinary()
+		assert.NoError(t, err)
+
+		// 任意截断都应返回错误而不是 panic
+		for i := 0; i < len(data); i++ {
+			restored := NewZSet()
+			restored.Add("keep", 1)
+			assert.ErrorIs(t, restored.UnmarshalBinary(data[:i]), ErrInvalidBinary)
+			assert.Equal(t, map[string]float64{"keep": 1}, restored.dict)
+		}
+
+		// 多余的尾部数据
+		assert.ErrorIs(t, NewZSet().UnmarshalBinary(append(data, 0)), ErrInvalidBinary)
+
+		// 声明的数量远大于实际数据
+		huge := make([]byte, 8)
+		binary.BigEndian.PutUint64(huge, math.MaxUint64)
+		assert.ErrorIs(t, NewZSet().UnmarshalBinary(huge), ErrInvalidBinary)
+
+		// 元素长度超出剩余数据
+		bad := append([]byte(nil), data...)
+		binary.BigEndian.PutUint32(bad[16:20], math.MaxUint32)
+		assert.ErrorIs(t, NewZSet().UnmarshalBinary(bad), ErrInvalidBinary)
+
+		// NaN 分数
+		nan := append([]byte(nil), data...)
+		binary.BigEndian.PutUint64(nan[8:16], math.Float64bits(math.NaN()))
+		assert.ErrorIs(t, NewZSet().UnmarshalBinary(nan), ErrInvalidBinary)
+	})
+
+	t.Run("undecodable generic member", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("not a number", 1)
+		data, err := z.MarshalBinary()
+		assert.NoError(t, err)
+		assert.ErrorIs(t, NewZSetFunc(func(a, b int) bool { return a < b }).UnmarshalBinary(data), ErrInvalidBinary)
+	})
+}
+
+func TestZSet_Gob(t *testing.T) {
+	z := NewZSet()
+	z.Add("c", 3)
+	z.Add("a", 1)
+	z.Add("b", 1)
+	z.Add("d", -2.5)
+
+	t.Run("round trip", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, gob.NewEncoder(&buf).Encode(z))
+
+		restored := NewZSet()
+		restored.Add("stale", 1)
+		assert.NoError(t, gob.NewDecoder(&buf).Decode(restored))
+		assert.Equal(t, z.Len(), restored.Len())
+		assert.Equal(t, z.RangeByRank(0, -1, false), restored.RangeByRank(0, -1, false))
+		for _, ele := range []string{"a", "b", "c", "d"} {
+			assert.Equal(t, z.Rank(ele, false), restored.Rank(ele, false))
+		}
+	})
+
+	t.Run("struct field", func(t *testing.T) {
+		type snapshot struct {
+			Name  string
+			Board *ZSet[string]
+		}
+
+		var buf bytes.Buffer
+		assert.NoError(t, gob.NewEncoder(&buf).Encode(snapshot{Name: "daily", Board: z}))
+
+		var restored snapshot
+		assert.NoError(t, gob.NewDecoder(&buf).Decode(&restored))
+		assert.Equal(t, "daily", restored.Name)
+		assert.Equal(t, z.RangeByRank(0, -1, false), restored.Board.RangeByRank(0, -1, false))
+		assert.NoError(t, restored.Board.Validate())
+	})
+}
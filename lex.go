@@ -0,0 +1,189 @@
+package zset
+
+// 字典序范围查询，语义同 Redis ZRANGEBYLEX / ZLEXCOUNT。
+// 这些操作按跳跃表中元素的排序规则比较元素，只有当所有元素分数相同时结果才有意义；
+// 分数不同时跳跃表按 (score, ele) 排序，元素顺序不再是单纯的字典序，结果未定义。
+
+// 字符串元素的无界哨兵：LexMin 表示不设下界，LexMax 表示不设上界，此时对应的包含标志被忽略。
+const (
+	LexMin = "-"
+	LexMax = "+"
+)
+
+// lexRange 表示一个字典序范围。
+type lexRange[M comparable] struct {
+	min, max       M
+	minInc, maxInc bool
+	minInf, maxInf bool
+	less           func(a, b M) bool
+}
+
+// newLexRange 根据上下界创建字典序范围，字符串元素的 LexMin / LexMax 被解析为无界。
+func newLexRange[M comparable](min, max M, minInclusive, maxInclusive bool, less func(a, b M) bool) lexRange[M] {
+	r := lexRange[M]{
+		min:    min,
+		max:    max,
+		minInc: minInclusive,
+		maxInc: maxInclusive,
+		less:   less,
+	}
+	if s, ok := any(min).(string); ok && s == LexMin {
+		r.minInf = true
+	}
+	if s, ok := any(max).(string); ok && s == LexMax {
+		r.maxInf = true
+	}
+	return r
+}
+
+// empty 判断范围是否必然为空。
+func (r lexRange[M]) empty() bool {
+	if r.minInf || r.maxInf {
+		return false
+	}
+	if r.less(r.max, r.min) {
+		return true
+	}
+	// 上下界相同且任一端为开区间
+	return !r.less(r.min, r.max) && (!r.minInc || !r.maxInc)
+}
+
+// gteMin 判断元素是否满足下界。
+func (r lexRange[M]) gteMin(ele M) bool {
+	if r.minInf {
+		return true
+	}
+	if r.minInc {
+		return !r.less(ele, r.min)
+	}
+	return r.less(r.min, ele)
+}
+
+// lteMax 判断元素是否满足上界。
+func (r lexRange[M]) lteMax(ele M) bool {
+	if r.maxInf {
+		return true
+	}
+	if r.maxInc {
+		return !r.less(r.max, ele)
+	}
+	return r.less(ele, r.max)
+}
+
+// lastBefore 查找跳跃表中最后一个满足 pred 的节点，要求满足 pred 的节点构成跳跃表的前缀。
+// 返回该节点（没有节点满足时为头节点）和满足 pred 的节点数量。
+func (sl *skiplist[M]) lastBefore(pred func(x *skiplistNode[M]) bool) (*skiplistNode[M], uint64) {
+	var count uint64 = 0
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && pred(x.level[i].forward) {
+			count += x.level[i].span
+			x = x.level[i].forward
+		}
+	}
+	return x, count
+}
+
+// RangeByLex 按元素的字典序范围获取元素，语义同 Redis ZRANGEBYLEX。
+// 只有当所有元素分数相同时结果才有意义，按跳跃表的排序规则比较元素。
+// min: 范围的下界，字符串元素可传入 LexMin 表示不设下界。
+// max: 范围的上界，字符串元素可传入 LexMax 表示不设上界。
+// minInclusive: 是否包含等于 min 的元素。
+// maxInclusive: 是否包含等于 max 的元素。
+// 返回按顺序排列的元素列表，范围为空时返回 nil。
+func (z *ZSet[M]) RangeByLex(min, max M, minInclusive, maxInclusive bool) []M {
+	z.rlock()
+	defer z.runlock()
+
+	r := newLexRange(min, max, minInclusive, maxInclusive, z.zsl.less)
+	if r.empty() {
+		return nil
+	}
+
+	x, _ := z.zsl.lastBefore(func(x *skiplistNode[M]) bool { return !r.gteMin(x.ele) })
+
+	var result []M
+	for x = x.level[0].forward; x != nil && r.lteMax(x.ele); x = x.level[0].forward {
+		result = append(result, x.ele)
+	}
+	return result
+}
+
+// LexCount 统计元素字典序位于范围内的元素数量，语义同 Redis ZLEXCOUNT。
+// 通过跳跃表跨度计算两端的排名，O(log n)，不构建结果切片。
+// 只有当所有元素分数相同时结果才有意义，分数不同时结果未定义。
+// min: 范围的下界，字符串元素可传入 LexMin 表示不设下界。
+// max: 范围的上界，字符串元素可传入 LexMax 表示不设上界。
+// minInclusive: 是否包含等于 min 的元素。
+// maxInclusive: 是否包含等于 max 的元素。
+// 返回满足条件的元素数量。
+func (z *ZSet[M]) LexCount(min, max M, minInclusive, maxInclusive bool) int64 {
+	z.rlock()
+	defer z.runlock()
+
+	r := newLexRange(min, max, minInclusive, maxInclusive, z.zsl.less)
+	if r.empty() {
+		return 0
+	}
+
+	_, below := z.zsl.lastBefore(func(x *skiplistNode[M]) bool { return !r.gteMin(x.ele) })
+	_, upTo := z.zsl.lastBefore(func(x *skiplistNode[M]) bool { return r.lteMax(x.ele) })
+	if upTo <= below {
+		return 0
+	}
+	return int64(upTo - below)
+}
+
+// deleteRangeByLex 删除跳跃表中元素位于字典序范围内的节点，并同步删除哈希表中的元素。
+// r: 字典序范围。
+// dict: 需要同步删除元素的哈希表。
+// payloads: 需要同步删除元素的载荷映射，可以为 nil。
+// 返回删除的节点数量。
+func (sl *skiplist[M]) deleteRangeByLex(r lexRange[M], dict map[M]float64, payloads map[M]any) uint64 {
+	var updateBuf [maxLevelLimit]*skiplistNode[M]
+	update := updateBuf[:sl.maxLevel]
+	var removed uint64 = 0
+
+	// 查找最后一个不满足下界的节点
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !r.gteMin(x.level[i].forward.ele) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	// 逐个删除区间内的节点
+	x = x.level[0].forward
+	for x != nil && r.lteMax(x.ele) {
+		next := x.level[0].forward
+		sl.deleteNode(x, update)
+		delete(dict, x.ele)
+		delete(payloads, x.ele)
+		sl.freeNode(x)
+		removed++
+		x = next
+	}
+
+	return removed
+}
+
+// RemoveRangeByLex 删除元素字典序位于范围内的所有元素，语义同 Redis ZREMRANGEBYLEX。
+// 只有当所有元素分数相同时结果才有意义；分数不同时跳跃表中满足范围的元素不一定连续，
+// 只会删除从第一个满足下界的元素开始、直到第一个超出上界的元素之前的连续一段。
+// min: 范围的下界，字符串元素可传入 LexMin 表示不设下界。
+// max: 范围的上界，字符串元素可传入 LexMax 表示不设上界。
+// minInclusive: 是否包含等于 min 的元素。
+// maxInclusive: 是否包含等于 max 的元素。
+// 返回删除的元素数量。
+func (z *ZSet[M]) RemoveRangeByLex(min, max M, minInclusive, maxInclusive bool) int {
+	z.lock()
+	defer z.unlock()
+
+	r := newLexRange(min, max, minInclusive, maxInclusive, z.zsl.less)
+	if r.empty() {
+		return 0
+	}
+
+	return int(z.zsl.deleteRangeByLex(r, z.dict, z.payloads))
+}
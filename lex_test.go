@@ -0,0 +1,119 @@
+package zset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newLexZSet 创建所有元素分数均为 0 的集合，供字典序测试使用。
+func newLexZSet(members ...string) *ZSet[string] {
+	z := NewZSet()
+	for _, m := range members {
+		z.Add(m, 0)
+	}
+	return z
+}
+
+func TestZSet_RangeByLex(t *testing.T) {
+	z := newLexZSet("a", "b", "c", "d", "e", "f", "g")
+
+	tests := []struct {
+		name         string
+		min          string
+		max          string
+		minInclusive bool
+		maxInclusive bool
+		expected     []string
+	}{
+		{"unbounded", LexMin, LexMax, false, false, []string{"a", "b", "c", "d", "e", "f", "g"}},
+		{"inclusive both", "b", "d", true, true, []string{"b", "c", "d"}},
+		{"exclusive both", "b", "d", false, false, []string{"c"}},
+		{"exclusive min", "b", "d", false, true, []string{"c", "d"}},
+		{"exclusive max", "b", "d", true, false, []string{"b", "c"}},
+		{"unbounded min", LexMin, "c", true, false, []string{"a", "b"}},
+		{"unbounded max", "e", LexMax, false, true, []string{"f", "g"}},
+		{"bounds between members", "aa", "cc", true, true, []string{"b", "c"}},
+		{"single member", "c", "c", true, true, []string{"c"}},
+		{"equal bounds exclusive", "c", "c", true, false, nil},
+		{"inverted", "d", "b", true, true, nil},
+		{"beyond all", "h", LexMax, true, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, z.RangeByLex(tt.min, tt.max, tt.minInclusive, tt.maxInclusive))
+		})
+	}
+
+	assert.Nil(t, NewZSet().RangeByLex(LexMin, LexMax, true, true))
+}
+
+func TestZSet_LexCount(t *testing.T) {
+	z := newLexZSet("a", "b", "c", "d", "e", "f", "g")
+
+	tests := []struct {
+		name         string
+		min          string
+		max          string
+		minInclusive bool
+		maxInclusive bool
+		expected     int64
+	}{
+		{"unbounded", LexMin, LexMax, false, false, 7},
+		{"inclusive both", "b", "d", true, true, 3},
+		{"exclusive both", "b", "d", false, false, 1},
+		{"exclusive min", "b", "d", false, true, 2},
+		{"exclusive max", "b", "d", true, false, 2},
+		{"unbounded min", LexMin, "c", true, true, 3},
+		{"unbounded max", "e", LexMax, true, true, 3},
+		{"empty window", "bb", "bc", true, true, 0},
+		{"equal bounds exclusive", "c", "c", false, true, 0},
+		{"inverted", "d", "b", true, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, z.LexCount(tt.min, tt.max, tt.minInclusive, tt.maxInclusive))
+			assert.Equal(t, int(tt.expected), len(z.RangeByLex(tt.min, tt.max, tt.minInclusive, tt.maxInclusive)))
+		})
+	}
+
+	assert.Equal(t, int64(0), NewZSet().LexCount(LexMin, LexMax, true, true))
+}
+
+func TestZSet_RemoveRangeByLex(t *testing.T) {
+	tests := []struct {
+		name         string
+		min          string
+		max          string
+		minInclusive bool
+		maxInclusive bool
+		removed      int
+		survivors    []string
+	}{
+		{"inclusive both", "b", "d", true, true, 3, []string{"a", "e", "f"}},
+		{"exclusive both", "b", "d", false, false, 1, []string{"a", "b", "d", "e", "f"}},
+		{"exclusive min", "b", "d", false, true, 2, []string{"a", "b", "e", "f"}},
+		{"exclusive max", "b", "d", true, false, 2, []string{"a", "d", "e", "f"}},
+		{"unbounded min", LexMin, "b", true, true, 2, []string{"c", "d", "e", "f"}},
+		{"unbounded max", "e", LexMax, false, true, 1, []string{"a", "b", "c", "d", "e"}},
+		{"all", LexMin, LexMax, true, true, 6, nil},
+		{"empty window", "bb", "bc", true, true, 0, []string{"a", "b", "c", "d", "e", "f"}},
+		{"inverted", "d", "b", true, true, 0, []string{"a", "b", "c", "d", "e", "f"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := newLexZSet("a", "b", "c", "d", "e", "f")
+			assert.Equal(t, tt.removed, z.RemoveRangeByLex(tt.min, tt.max, tt.minInclusive, tt.maxInclusive))
+			assert.Equal(t, uint64(len(tt.survivors)), z.Len())
+			if tt.survivors == nil {
+				assert.Empty(t, z.Members(false))
+			} else {
+				assert.Equal(t, tt.survivors, z.Members(false))
+			}
+			assert.NoError(t, z.Validate())
+		})
+	}
+}
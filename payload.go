@@ -0,0 +1,169 @@
+package zset
+
+// 元素载荷：每个元素可以附带一个任意类型的载荷，保存在集合的载荷映射中，不参与排序。
+// 载荷映射只在 NewZSetWithPayload 创建或首次通过 AddP 设置非 nil 载荷时分配，不使用载荷的集合没有额外开销。
+// 载荷随元素一起移动：分数更新、Clone、RebuildWithMaxLevel、MergeCaseInsensitive 都会保留载荷；
+// 集合运算（Union、Intersect、Diff 等）和序列化只处理元素与分数，不保留载荷。
+
+// NewZSetWithPayload 创建一个用于保存载荷的有序集合，元素为字符串，分数相同时按字典序排序。
+// 与 NewZSet 的区别是预先分配载荷映射；其他集合在首次调用 AddP 时分配。
+// 返回新创建的 ZSet 指针。
+func NewZSetWithPayload() *ZSet[string] {
+	z := NewZSet()
+	z.payloads = make(map[string]any)
+	return z
+}
+
+// setPayload 设置元素的载荷，调用方需持有写锁且元素已存在。
+// payload 为 nil 时删除载荷条目，否则按需分配载荷映射。
+func (z *ZSet[M]) setPayload(ele M, payload any) {
+	if payload == nil {
+		delete(z.payloads, ele)
+		return
+	}
+	if z.payloads == nil {
+		z.payloads = make(map[M]any)
+	}
+	z.payloads[ele] = payload
+}
+
+// AddP 向 ZSet 中添加元素或更新已存在元素的分数，同时设置元素的载荷。
+// ele: 要添加的元素。
+// score: 元素的分数，为 NaN 时不做任何修改。
+// payload: 元素的载荷，覆盖原有载荷。
+// 返回元素是否为新添加。
+func (z *ZSet[M]) AddP(ele M, score float64, payload any) bool {
+	z.lock()
+	defer z.unlock()
+
+	x, added := z.upsert(ele, score)
+	if x != nil {
+		z.setPayload(ele, payload)
+	}
+	return added
+}
+
+// Payload 获取指定元素的载荷。
+// ele: 要获取载荷的元素。
+// 返回元素的载荷（未设置时为 nil）和元素是否存在的标志。
+func (z *ZSet[M]) Payload(ele M) (any, bool) {
+	z.rlock()
+	defer z.runlock()
+
+	if _, exists := z.dict[ele]; !exists {
+		return nil, false
+	}
+	return z.payloads[ele], true
+}
+
+// GetByRankP 获取 ZSet 中指定排名的元素及其载荷。
+// rank: 要获取的排名（从 0 开始），负数表示从末尾倒数，语义同 GetByRank。
+// reverse: 是否按降序排名。
+// 返回元素、元素的分数、元素的载荷和元素是否存在的标志。
+func (z *ZSet[M]) GetByRankP(rank int64, reverse bool) (M, float64, any, bool) {
+	z.rlock()
+	defer z.runlock()
+
+	var zero M
+	rank, ok := normalizeRank(rank, z.zsl.length)
+	if !ok {
+		return zero, 0, nil, false
+	}
+
+	if reverse {
+		rank = int64(z.zsl.length) - 1 - rank
+	}
+
+	n := z.zsl.getElementByRank(uint64(rank + 1))
+	if n == nil {
+		return zero, 0, nil, false
+	}
+
+	return n.ele, n.score, z.payloads[n.ele], true
+}
+
+// RangeByRankP 获取排名位于 [start, stop] 的元素及其载荷，语义与 RangeByRank 相同。
+// start: 起始排名（从 0 开始），负数表示从末尾倒数。
+// stop: 结束排名（包含），负数表示从末尾倒数。
+// reverse: 是否按降序排名。
+// 返回与 RangeByRank 顺序一致的元素列表。
+func (z *ZSet[M]) RangeByRankP(start, stop int64, reverse bool) []struct {
+	Member  M
+	Score   float64
+	Payload any
+} {
+	z.rlock()
+	defer z.runlock()
+
+	var result []struct {
+		Member  M
+		Score   float64
+		Payload any
+	}
+	if first, last, ok := normalizeRange(start, stop, z.zsl.length); ok {
+		result = make([]struct {
+			Member  M
+			Score   float64
+			Payload any
+		}, 0, last-first+1)
+	}
+	z.walkRank(start, stop, reverse, func(x *skiplistNode[M], _ int64) bool {
+		result = append(result, struct {
+			Member  M
+			Score   float64
+			Payload any
+		}{
+			Member:  x.ele,
+			Score:   x.score,
+			Payload: z.payloads[x.ele],
+		})
+		return true
+	})
+	return result
+}
+
+// RangeByScoreP 按分数范围获取元素及其载荷，语义与 RangeByScore 相同。
+// min: 分数范围的最小值（包含）。
+// max: 分数范围的最大值（包含）。
+// offset: 跳过的元素数量。
+// count: 要获取的元素数量，-1 表示获取所有符合条件的元素。
+// 返回符合条件的元素列表，min 大于 max 时返回 nil。
+func (z *ZSet[M]) RangeByScoreP(min, max float64, offset, count int64) []struct {
+	Member  M
+	Score   float64
+	Payload any
+} {
+	z.rlock()
+	defer z.runlock()
+
+	var result []struct {
+		Member  M
+		Score   float64
+		Payload any
+	}
+	if !(min <= max) {
+		return result
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	// 从最后一个分数小于 min 的节点之后开始
+	x := z.zsl.lastBelow(min, false).level[0].forward
+	for ; x != nil && offset > 0 && x.score <= max; offset-- {
+		x = x.level[0].forward
+	}
+	for ; x != nil && count != 0 && x.score <= max; x = x.level[0].forward {
+		result = append(result, struct {
+			Member  M
+			Score   float64
+			Payload any
+		}{
+			Member:  x.ele,
+			Score:   x.score,
+			Payload: z.payloads[x.ele],
+		})
+		count--
+	}
+	return result
+}
@@ -0,0 +1,305 @@
+package zset
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZSet_Payload(t *testing.T) {
+	z := NewZSetWithPayload()
+	assert.True(t, z.AddP("a", 1, "pa"))
+	assert.True(t, z.AddP("b", 2, 42))
+	assert.True(t, z.AddP("c", 3, nil))
+	z.Add("d", 4)
+
+	tests := []struct {
+		name     string
+		ele      string
+		expected any
+		exists   bool
+	}{
+		{"string payload", "a", "pa", true},
+		{"int payload", "b", 42, true},
+		{"explicit nil", "c", nil, true},
+		{"added without payload", "d", nil, true},
+		{"missing member", "x", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, exists := z.Payload(tt.ele)
+			assert.Equal(t, tt.exists, exists)
+			assert.Equal(t, tt.expected, payload)
+		})
+	}
+
+	// 再次 AddP 覆盖载荷但不是新添加
+	assert.False(t, z.AddP("a", 1, "pa2"))
+	payload, _ := z.Payload("a")
+	assert.Equal(t, "pa2", payload)
+
+	// NaN 分数不修改元素也不修改载荷
+	assert.False(t, z.AddP("a", math.NaN(), "nan"))
+	payload, _ = z.Payload("a")
+	assert.Equal(t, "pa2", payload)
+	assert.NoError(t, z.Validate())
+}
+
+func TestZSet_PayloadFollowsScoreUpdates(t *testing.T) {
+	z := NewZSetWithPayload()
+	z.AddP("a", 1, "pa")
+	z.AddP("b", 2, "pb")
+	z.AddP("c", 3, "pc")
+
+	tests := []struct {
+		name   string
+		update func()
+		order  []string
+	}{
+		{"add in place", func() { z.Add("b", 2.5) }, []string{"a", "b", "c"}},
+		{"add moves node", func() { z.Add("a", 10) }, []string{"b", "c", "a"}},
+		{"incrby moves node", func() { z.IncrBy("c", -10) }, []string{"c", "b", "a"}},
+		{"update score", func() { assert.NoError(t, z.UpdateScore("b", -20)) }, []string{"b", "c", "a"}},
+		{"addopt", func() { z.AddOpt("a", -30, AddOptions{XX: true}) }, []string{"a", "b", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.update()
+			assert.Equal(t, tt.order, z.Members(false))
+			for _, m := range []string{"a", "b", "c"} {
+				payload, exists := z.Payload(m)
+				assert.True(t, exists)
+				assert.Equal(t, "p"+m, payload)
+			}
+			assert.NoError(t, z.Validate())
+		})
+	}
+}
+
+func TestZSet_PayloadRangeQueries(t *testing.T) {
+	z := NewZSetWithPayload()
+	for i, m := range []string{"a", "b", "c", "d", "e"} {
+		z.AddP(m, float64(i+1), "p"+m)
+	}
+
+	t.Run("get by rank", func(t *testing.T) {
+		ele, score, payload, ok := z.GetByRankP(1, false)
+		assert.True(t, ok)
+		assert.Equal(t, "b", ele)
+		assert.Equal(t, 2.0, score)
+		assert.Equal(t, "pb", payload)
+
+		ele, _, payload, ok = z.GetByRankP(0, true)
+		assert.True(t, ok)
+		assert.Equal(t, "e", ele)
+		assert.Equal(t, "pe", payload)
+
+		ele, _, payload, ok = z.GetByRankP(-1, false)
+		assert.True(t, ok)
+		assert.Equal(t, "e", ele)
+		assert.Equal(t, "pe", payload)
+
+		_, _, _, ok = z.GetByRankP(5, false)
+		assert.False(t, ok)
+		_, _, _, ok = z.GetByRankP(-6, true)
+		assert.False(t, ok)
+	})
+
+	t.Run("range by rank", func(t *testing.T) {
+		tests := []struct {
+			start, stop int64
+			reverse     bool
+			expected    []string
+		}{
+			{0, -1, false, []string{"a", "b", "c", "d", "e"}},
+			{1, 2, true, []string{"d", "c"}},
+			{-2, -1, false, []string{"d", "e"}},
+			{3, 1, false, nil},
+		}
+		for _, tt := range tests {
+			items := z.RangeByRankP(tt.start, tt.stop, tt.reverse)
+			assert.Len(t, items, len(tt.expected))
+			for i, item := range items {
+				assert.Equal(t, tt.expected[i], item.Member)
+				assert.Equal(t, "p"+item.Member, item.Payload)
+				score, _ := z.Score(item.Member)
+				assert.Equal(t, score, item.Score)
+			}
+		}
+	})
+
+	t.Run("range by score", func(t *testing.T) {
+		tests := []struct {
+			min, max      float64
+			offset, count int64
+			expected      []string
+		}{
+			{2, 4, 0, -1, []string{"b", "c", "d"}},
+			{2, 4, 1, 1, []string{"c"}},
+			{0, 10, 3, -1, []string{"d", "e"}},
+			{2.5, 2.7, 0, -1, nil},
+			{4, 2, 0, -1, nil},
+		}
+		for _, tt := range tests {
+			items := z.RangeByScoreP(tt.min, tt.max, tt.offset, tt.count)
+			assert.Len(t, items, len(tt.expected))
+			expected := z.RangeByScore(tt.min, tt.max, tt.offset, tt.count)
+			for i, item := range items {
+				assert.Equal(t, tt.expected[i], item.Member)
+				assert.Equal(t, expected[i].Member, item.Member)
+				assert.Equal(t, "p"+item.Member, item.Payload)
+			}
+		}
+	})
+}
+
+func TestZSet_PayloadLifecycle(t *testing.T) {
+	z := NewZSetWithPayload()
+	z.AddP("a", 1, "pa")
+	z.AddP("b", 2, "pb")
+
+	// Clone 和 RebuildWithMaxLevel 保留载荷
+	c := z.Clone()
+	payload, _ := c.Payload("b")
+	assert.Equal(t, "pb", payload)
+	assert.NoError(t, z.RebuildWithMaxLevel(8))
+	payload, _ = z.Payload("a")
+	assert.Equal(t, "pa", payload)
+
+	// 删除后重新添加的元素不会继承旧载荷，即使复用了空闲节点
+	assert.True(t, z.Remove("a"))
+	_, exists := z.Payload("a")
+	assert.False(t, exists)
+	z.Add("a", 1)
+	payload, exists = z.Payload("a")
+	assert.True(t, exists)
+	assert.Nil(t, payload)
+
+	z.PopMax()
+	z.Add("b", 2)
+	payload, _ = z.Payload("b")
+	assert.Nil(t, payload)
+	assert.NoError(t, z.Validate())
+}
+
+func TestZSet_PayloadMergeCaseInsensitive(t *testing.T) {
+	z := NewZSetWithPayload()
+	z.AddP("Alice", 5, "winner")
+	z.AddP("alice", 3, "loser")
+	z.AddP("Bob", 1, "pb")
+	z.AddP("Carol", 2, "c1")
+	z.AddP("carol", 2, "c2")
+	z.AddP("keep", 4, "pk")
+	z.Add("plain", 6)
+
+	assert.Equal(t, 2, z.MergeCaseInsensitive())
+	assert.NoError(t, z.Validate())
+
+	tests := []struct {
+		ele      string
+		expected any
+	}{
+		{"alice", "winner"},
+		{"bob", "pb"},
+		{"carol", "c1"}, // 分数相同时保留排序靠前的 "Carol" 的载荷
+		{"keep", "pk"},
+		{"plain", nil},
+	}
+	for _, tt := range tests {
+		payload, exists := z.Payload(tt.ele)
+		assert.True(t, exists, tt.ele)
+		assert.Equal(t, tt.expected, payload, tt.ele)
+	}
+}
+
+func TestZSet_PayloadStorage(t *testing.T) {
+	// 只有使用载荷的集合才分配载荷映射
+	assert.Nil(t, NewZSet().payloads)
+	assert.NotNil(t, NewZSetWithPayload().payloads)
+
+	z := NewZSet()
+	z.Add("a", 1)
+	assert.Nil(t, z.payloads)
+	z.AddP("b", 2, nil)
+	assert.Nil(t, z.payloads)
+	z.AddP("c", 3, "pc")
+	assert.Equal(t, map[string]any{"c": "pc"}, z.payloads)
+	z.AddP("c", 3, nil)
+	assert.Empty(t, z.payloads)
+
+	setup := func() *ZSet[string] {
+		z := NewZSetWithPayload()
+		for i, m := range []string{"a", "b", "c", "d", "e", "f"} {
+			z.AddP(m, float64(i), "p"+m)
+		}
+		return z
+	}
+
+	// 各种删除方式都同步删除载荷
+	tests := []struct {
+		name   string
+		remove func(z *ZSet[string])
+		left   []string
+	}{
+		{"remove", func(z *ZSet[string]) { z.Remove("a") }, []string{"b", "c", "d", "e", "f"}},
+		{"pop min", func(z *ZSet[string]) { z.PopMin() }, []string{"b", "c", "d", "e", "f"}},
+		{"remove range by rank", func(z *ZSet[string]) { z.RemoveRangeByRank(0, 1) }, []string{"c", "d", "e", "f"}},
+		{"remove range by score", func(z *ZSet[string]) { z.RemoveRangeByScore(1, 3) }, []string{"a", "e", "f"}},
+		{"remove range by lex", func(z *ZSet[string]) { z.RemoveRangeByLex(LexMin, "b", true, true) }, []string{"c", "d", "e", "f"}},
+		{"trim to size", func(z *ZSet[string]) { z.TrimToSize(2, true) }, []string{"e", "f"}},
+		{"clear", func(z *ZSet[string]) { z.Clear() }, nil},
+		{"replace all", func(z *ZSet[string]) { z.ReplaceAll(map[string]float64{"a": 1}) }, nil},
+		{"intersect into", func(z *ZSet[string]) {
+			z.IntersectInto(z, []*ZSet[string]{newZSetOf(map[string]float64{"a": 1})}, nil, AggregateSum)
+		}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := setup()
+			tt.remove(z)
+			assert.NoError(t, z.Validate())
+			assert.Len(t, z.payloads, len(tt.left))
+			for _, m := range tt.left {
+				payload, _ := z.Payload(m)
+				assert.Equal(t, "p"+m, payload)
+			}
+		})
+	}
+
+	t.Run("move and rename", func(t *testing.T) {
+		z, dst := setup(), NewZSet()
+		assert.True(t, z.MoveTo(dst, "a"))
+		assert.True(t, z.Rename("b", "bb"))
+		assert.NoError(t, z.Validate())
+		assert.NoError(t, dst.Validate())
+
+		payload, _ := dst.Payload("a")
+		assert.Equal(t, "pa", payload)
+		payload, _ = z.Payload("bb")
+		assert.Equal(t, "pb", payload)
+		_, exists := z.payloads["b"]
+		assert.False(t, exists)
+
+		// 移动没有载荷的元素会清除目标中同名元素的载荷
+		dst.AddP("c", 0, "old")
+		z.Add("c", 5)
+		z.AddP("c", 5, nil)
+		assert.True(t, z.MoveTo(dst, "c"))
+		payload, exists = dst.Payload("c")
+		assert.True(t, exists)
+		assert.Nil(t, payload)
+	})
+
+	t.Run("clone is independent", func(t *testing.T) {
+		z := setup()
+		c := z.Clone()
+		c.AddP("a", 0, "changed")
+		payload, _ := z.Payload("a")
+		assert.Equal(t, "pa", payload)
+		assert.Nil(t, NewZSet().Clone().payloads)
+	})
+}
@@ -0,0 +1,254 @@
+package zset
+
+import (
+	"math"
+	"slices"
+)
+
+// AggregateFunc 定义集合运算中同一元素的多个分数如何合并。
+type AggregateFunc int
+
+const (
+	// AggregateSum 对分数求和。
+	AggregateSum AggregateFunc = iota
+	// AggregateMin 取最小分数。
+	AggregateMin
+	// AggregateMax 取最大分数。
+	AggregateMax
+)
+
+// apply 按聚合方式合并两个分数。
+// a: 已聚合的分数。
+// b: 新的分数。
+// 返回合并后的分数；与 Redis 一致，+Inf 与 -Inf 求和得到的 NaN 视为 0。
+func (agg AggregateFunc) apply(a, b float64) float64 {
+	switch agg {
+	case AggregateMin:
+		if b < a {
+			return b
+		}
+		return a
+	case AggregateMax:
+		if b > a {
+			return b
+		}
+		return a
+	default:
+		if sum := a + b; !math.IsNaN(sum) {
+			return sum
+		}
+		return 0
+	}
+}
+
+// weightScore 计算元素的加权分数。
+// score: 元素在输入集合中的分数。
+// weight: 输入集合的权重。
+// 返回 score * weight；与 Redis 一致，±Inf 乘以 0 得到的 NaN 视为 0。
+func weightScore(score, weight float64) float64 {
+	if weighted := score * weight; !math.IsNaN(weighted) {
+		return weighted
+	}
+	return 0
+}
+
+// emptyLike 创建一个与 z 具有相同排序规则、最大层级和层级概率的空集合，调用方需持有 z 的锁。
+// 返回新创建的 ZSet 指针。
+func (z *ZSet[M]) emptyLike() *ZSet[M] {
+	e := &ZSet[M]{
+		dict: make(map[M]float64),
+		zsl:  createSkiplist(z.zsl.maxLevel, z.zsl.less),
+	}
+	e.zsl.p = z.zsl.p
+	return e
+}
+
+// Union 计算两个集合的并集，语义同 Redis ZUNIONSTORE。
+// a: 第一个集合，结果沿用它的排序规则。
+// b: 第二个集合。
+// weights: 两个集合各自的分数权重，元素的分数先乘以对应权重再参与聚合。
+// aggregate: 同时存在于两个集合中的元素的分数聚合方式。
+// 返回新的 ZSet，只存在于一个集合中的元素直接使用其加权分数。
+func Union[M comparable](a, b *ZSet[M], weights [2]float64, aggregate AggregateFunc) *ZSet[M] {
+	unlock := rlockAll(a, b)
+	defer unlock()
+
+	result := a.emptyLike()
+	for ele, score := range a.dict {
+		result.dict[ele] = weightScore(score, weights[0])
+	}
+	for ele, score := range b.dict {
+		weighted := weightScore(score, weights[1])
+		if old, exists := result.dict[ele]; exists {
+			weighted = aggregate.apply(old, weighted)
+		}
+		result.dict[ele] = weighted
+	}
+
+	for ele, score := range result.dict {
+		result.zsl.insert(score, ele)
+	}
+	return result
+}
+
+// Intersect 计算多个集合的交集，语义同 Redis ZINTERSTORE。
+// sets: 参与运算的集合，结果沿用第一个集合的排序规则。
+// weights: 各集合的分数权重，nil 表示全部为 1；长度与 sets 不一致时 panic。
+// aggregate: 各集合中同一元素分数的聚合方式。
+// 返回只包含所有集合中都存在的元素的新 ZSet；sets 为空时返回 nil。
+// 遍历元素最少的集合，并通过其他集合的哈希表判断元素是否存在。
+func Intersect[M comparable](sets []*ZSet[M], weights []float64, aggregate AggregateFunc) *ZSet[M] {
+	if weights != nil && len(weights) != len(sets) {
+		panic("zset: Intersect weights length does not match sets length")
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+
+	unlock := rlockAll(sets...)
+	defer unlock()
+
+	return intersect(sets[0].emptyLike(), sets, weights, aggregate)
+}
+
+// IntersectInto 计算 z 与 others 的交集并写入 dst，语义同 Redis ZINTERSTORE，适合频繁重算交集的场景。
+// dst 原有内容被丢弃，复用其哈希表和跳跃表节点，避免每次分配新的结果集合；结果沿用 dst 的排序规则。
+// dst: 存放结果的集合，可以是 z 或 others 中的集合。
+// others: 与 z 求交集的其他集合。
+// weights: 各集合的分数权重，依次对应 z 和 others，nil 表示全部为 1；长度与 1+len(others) 不一致时 panic。
+// aggregate: 各集合中同一元素分数的聚合方式。
+func (z *ZSet[M]) IntersectInto(dst *ZSet[M], others []*ZSet[M], weights []float64, aggregate AggregateFunc) {
+	sets := append([]*ZSet[M]{z}, others...)
+	if weights != nil && len(weights) != len(sets) {
+		panic("zset: IntersectInto weights length does not match sets length")
+	}
+
+	unlock := lockInto(dst, sets...)
+	defer unlock()
+
+	if slices.Contains(sets, dst) {
+		// dst 同时是输入，先算到临时集合再替换，避免清空后丢失输入
+		result := intersect(dst.emptyLike(), sets, weights, aggregate)
+		result.zsl.rand = dst.zsl.rand
+		dst.dict, dst.zsl = result.dict, result.zsl
+		clear(dst.payloads)
+		return
+	}
+
+	dst.clear(true)
+	intersect(dst, sets, weights, aggregate)
+}
+
+// IntersectCard 统计所有集合中都存在的元素数量，语义同 Redis ZINTERCARD。
+// sets: 参与运算的集合。
+// limit: 计数上限，达到后立即停止遍历；小于等于 0 表示不设上限。
+// 返回交集的元素数量（不超过 limit），sets 为空时返回 0。
+// 遍历元素最少的集合，并通过其他集合的哈希表判断元素是否存在，不构建结果集合。
+func IntersectCard[M comparable](sets []*ZSet[M], limit int64) int64 {
+	if len(sets) == 0 {
+		return 0
+	}
+
+	unlock := rlockAll(sets...)
+	defer unlock()
+
+	// 找到元素最少的集合
+	smallest := 0
+	for i, z := range sets {
+		if len(z.dict) < len(sets[smallest].dict) {
+			smallest = i
+		}
+	}
+
+	var count int64 = 0
+	for ele := range sets[smallest].dict {
+		found := true
+		for i, z := range sets {
+			if i == smallest {
+				continue
+			}
+			if _, exists := z.dict[ele]; !exists {
+				found = false
+				break
+			}
+		}
+		if found {
+			count++
+			if limit > 0 && count >= limit {
+				break
+			}
+		}
+	}
+	return count
+}
+
+// intersect 将多个集合的交集写入空集合 dst，调用方需持有所有集合的锁。
+// dst: 存放结果的空集合。
+// sets: 参与运算的集合，不能为空。
+// weights: 各集合的分数权重，nil 表示全部为 1。
+// aggregate: 各集合中同一元素分数的聚合方式。
+// 返回 dst。
+func intersect[M comparable](dst *ZSet[M], sets []*ZSet[M], weights []float64, aggregate AggregateFunc) *ZSet[M] {
+	weight := func(i int) float64 {
+		if weights == nil {
+			return 1
+		}
+		return weights[i]
+	}
+
+	// 找到元素最少的集合
+	smallest := 0
+	for i, z := range sets {
+		if len(z.dict) < len(sets[smallest].dict) {
+			smallest = i
+		}
+	}
+
+	for ele := range sets[smallest].dict {
+		var score float64
+		found := true
+		for i, z := range sets {
+			s, exists := z.dict[ele]
+			if !exists {
+				found = false
+				break
+			}
+			if i == 0 {
+				score = weightScore(s, weight(i))
+			} else {
+				score = aggregate.apply(score, weightScore(s, weight(i)))
+			}
+		}
+		if found {
+			dst.dict[ele] = score
+			dst.zsl.insert(score, ele)
+		}
+	}
+
+	return dst
+}
+
+// Diff 计算集合的差集，语义同 Redis ZDIFFSTORE。
+// a: 被减集合，结果沿用它的排序规则和分数。
+// others: 减去的集合。
+// 返回只包含存在于 a 而不存在于任何 others 中的元素的新 ZSet。
+func Diff[M comparable](a *ZSet[M], others ...*ZSet[M]) *ZSet[M] {
+	unlock := rlockAll(append([]*ZSet[M]{a}, others...)...)
+	defer unlock()
+
+	result := a.emptyLike()
+	for x := a.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		excluded := false
+		for _, other := range others {
+			if _, exists := other.dict[x.ele]; exists {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			result.dict[x.ele] = x.score
+			result.zsl.insert(x.score, x.ele)
+		}
+	}
+	return result
+}
@@ -0,0 +1,439 @@
+package zset
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newZSetOf 以给定的元素和分数创建集合，供测试使用。
+func newZSetOf(members map[string]float64) *ZSet[string] {
+	z := NewZSet()
+	z.AddBatch(members)
+	return z
+}
+
+func TestUnion(t *testing.T) {
+	tests := []struct {
+		name      string
+		a         map[string]float64
+		b         map[string]float64
+		weights   [2]float64
+		aggregate AggregateFunc
+		expected  map[string]float64
+	}{
+		{
+			name:      "both empty",
+			weights:   [2]float64{1, 1},
+			aggregate: AggregateSum,
+			expected:  map[string]float64{},
+		},
+		{
+			name:      "disjoint sets",
+			a:         map[string]float64{"a": 1, "b": 2},
+			b:         map[string]float64{"c": 3},
+			weights:   [2]float64{1, 1},
+			aggregate: AggregateSum,
+			expected:  map[string]float64{"a": 1, "b": 2, "c": 3},
+		},
+		{
+			name:      "overlap sum",
+			a:         map[string]float64{"a": 1, "b": 2},
+			b:         map[string]float64{"b": 5, "c": 3},
+			weights:   [2]float64{1, 1},
+			aggregate: AggregateSum,
+			expected:  map[string]float64{"a": 1, "b": 7, "c": 3},
+		},
+		{
+			name:      "overlap min",
+			a:         map[string]float64{"a": 1, "b": 2},
+			b:         map[string]float64{"b": 5, "c": 3},
+			weights:   [2]float64{1, 1},
+			aggregate: AggregateMin,
+			expected:  map[string]float64{"a": 1, "b": 2, "c": 3},
+		},
+		{
+			name:      "overlap max",
+			a:         map[string]float64{"a": 1, "b": 2},
+			b:         map[string]float64{"b": 5, "c": 3},
+			weights:   [2]float64{1, 1},
+			aggregate: AggregateMax,
+			expected:  map[string]float64{"a": 1, "b": 5, "c": 3},
+		},
+		{
+			name:      "weights applied",
+			a:         map[string]float64{"a": 1, "b": 2},
+			b:         map[string]float64{"b": 5, "c": 3},
+			weights:   [2]float64{2, 0.5},
+			aggregate: AggregateSum,
+			expected:  map[string]float64{"a": 2, "b": 6.5, "c": 1.5},
+		},
+		{
+			name:      "weights applied before max",
+			a:         map[string]float64{"b": 2},
+			b:         map[string]float64{"b": 5},
+			weights:   [2]float64{3, 1},
+			aggregate: AggregateMax,
+			expected:  map[string]float64{"b": 6},
+		},
+		{
+			name:      "sum of opposite infinities is zero",
+			a:         map[string]float64{"x": math.Inf(1), "y": 1},
+			b:         map[string]float64{"x": math.Inf(-1), "y": 2},
+			weights:   [2]float64{1, 1},
+			aggregate: AggregateSum,
+			expected:  map[string]float64{"x": 0, "y": 3},
+		},
+		{
+			name:      "zero weight on infinity is zero",
+			a:         map[string]float64{"x": math.Inf(1), "y": math.Inf(-1)},
+			b:         map[string]float64{"z": math.Inf(-1)},
+			weights:   [2]float64{0, 0},
+			aggregate: AggregateMax,
+			expected:  map[string]float64{"x": 0, "y": 0, "z": 0},
+		},
+		{
+			name:      "infinity survives min and max",
+			a:         map[string]float64{"x": math.Inf(1)},
+			b:         map[string]float64{"x": math.Inf(-1)},
+			weights:   [2]float64{1, 1},
+			aggregate: AggregateMax,
+			expected:  map[string]float64{"x": math.Inf(1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := newZSetOf(tt.a), newZSetOf(tt.b)
+			result := Union(a, b, tt.weights, tt.aggregate)
+			assert.Equal(t, tt.expected, result.dict)
+			assert.Equal(t, uint64(len(tt.expected)), result.Len())
+
+			// 结果按分数有序，且输入集合未被修改
+			assert.NoError(t, result.Validate())
+			prev := math.Inf(-1)
+			result.ForEach(false, func(member string, score float64) bool {
+				assert.GreaterOrEqual(t, score, prev)
+				prev = score
+				return true
+			})
+			assert.Equal(t, uint64(len(tt.a)), a.Len())
+			assert.Equal(t, uint64(len(tt.b)), b.Len())
+		})
+	}
+
+	t.Run("same set twice", func(t *testing.T) {
+		z := newZSetOf(map[string]float64{"a": 1})
+		result := Union(z, z, [2]float64{1, 1}, AggregateSum)
+		assert.Equal(t, map[string]float64{"a": 2}, result.dict)
+	})
+}
+
+func TestIntersect(t *testing.T) {
+	tests := []struct {
+		name      string
+		sets      []map[string]float64
+		weights   []float64
+		aggregate AggregateFunc
+		expected  map[string]float64
+	}{
+		{
+			name: "three sets sum",
+			sets: []map[string]float64{
+				{"a": 1, "b": 2, "c": 3},
+				{"a": 10, "b": 20, "d": 40},
+				{"a": 100, "b": 200, "c": 300},
+			},
+			aggregate: AggregateSum,
+			expected:  map[string]float64{"a": 111, "b": 222},
+		},
+		{
+			name: "three sets min with weights",
+			sets: []map[string]float64{
+				{"a": 1, "b": 2},
+				{"a": 10, "b": 1},
+				{"a": 5, "b": 5},
+			},
+			weights:   []float64{10, 1, 1},
+			aggregate: AggregateMin,
+			expected:  map[string]float64{"a": 5, "b": 1},
+		},
+		{
+			name: "partial overlap max",
+			sets: []map[string]float64{
+				{"a": 1, "b": 2, "c": 3},
+				{"b": 5, "c": 1},
+			},
+			aggregate: AggregateMax,
+			expected:  map[string]float64{"b": 5, "c": 3},
+		},
+		{
+			name: "one empty set",
+			sets: []map[string]float64{
+				{"a": 1},
+				{},
+			},
+			aggregate: AggregateSum,
+			expected:  map[string]float64{},
+		},
+		{
+			name: "no overlap",
+			sets: []map[string]float64{
+				{"a": 1},
+				{"b": 1},
+			},
+			aggregate: AggregateSum,
+			expected:  map[string]float64{},
+		},
+		{
+			name:      "single set applies weight",
+			sets:      []map[string]float64{{"a": 1, "b": 2}},
+			weights:   []float64{3},
+			aggregate: AggregateSum,
+			expected:  map[string]float64{"a": 3, "b": 6},
+		},
+		{
+			name: "sum of opposite infinities is zero",
+			sets: []map[string]float64{
+				{"x": math.Inf(1), "y": 1},
+				{"x": math.Inf(-1), "y": 2},
+			},
+			aggregate: AggregateSum,
+			expected:  map[string]float64{"x": 0, "y": 3},
+		},
+		{
+			name: "zero weight on infinity is zero",
+			sets: []map[string]float64{
+				{"x": math.Inf(1), "y": math.Inf(-1)},
+				{"x": 2, "y": 3},
+			},
+			weights:   []float64{0, 1},
+			aggregate: AggregateMin,
+			expected:  map[string]float64{"x": 0, "y": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sets []*ZSet[string]
+			for _, m := range tt.sets {
+				sets = append(sets, newZSetOf(m))
+			}
+			result := Intersect(sets, tt.weights, tt.aggregate)
+			assert.Equal(t, tt.expected, result.dict)
+			assert.Equal(t, uint64(len(tt.expected)), result.Len())
+			assert.NoError(t, result.Validate())
+
+			// IntersectInto 得到相同的结果
+			dst := newZSetOf(map[string]float64{"stale": 1})
+			sets[0].IntersectInto(dst, sets[1:], tt.weights, tt.aggregate)
+			assert.Equal(t, tt.expected, dst.dict)
+			assert.NoError(t, dst.Validate())
+		})
+	}
+
+	t.Run("no sets", func(t *testing.T) {
+		assert.Nil(t, Intersect[string](nil, nil, AggregateSum))
+	})
+
+	t.Run("mismatched weights panic", func(t *testing.T) {
+		sets := []*ZSet[string]{newZSetOf(map[string]float64{"a": 1}), newZSetOf(map[string]float64{"a": 2})}
+		assert.Panics(t, func() {
+			Intersect(sets, []float64{1}, AggregateSum)
+		})
+	})
+}
+
+func TestZSet_IntersectInto(t *testing.T) {
+	a := newZSetOf(map[string]float64{"a": 1, "b": 2, "c": 3})
+	b := newZSetOf(map[string]float64{"a": 10, "b": 20, "d": 40})
+	c := newZSetOf(map[string]float64{"c": 5, "d": 6, "e": 7})
+	d := newZSetOf(map[string]float64{"c": 1, "d": 1})
+
+	t.Run("reuse destination", func(t *testing.T) {
+		dst := newZSetOf(map[string]float64{"stale": 100, "a": -1})
+
+		a.IntersectInto(dst, []*ZSet[string]{b}, nil, AggregateSum)
+		assert.Equal(t, map[string]float64{"a": 11, "b": 22}, dst.ToMap())
+		assert.Equal(t, []string{"a", "b"}, dst.Members(false))
+		assert.NoError(t, dst.Validate())
+
+		// 第二次计算与第一次互不影响，也不残留第一次的结果
+		c.IntersectInto(dst, []*ZSet[string]{d}, []float64{1, 10}, AggregateMax)
+		assert.Equal(t, map[string]float64{"c": 10, "d": 10}, dst.ToMap())
+		assert.Equal(t, []string{"c", "d"}, dst.Members(false))
+		assert.NoError(t, dst.Validate())
+
+		// 与 Intersect 的结果一致，输入集合不被修改
+		expected := Intersect([]*ZSet[string]{a, b}, nil, AggregateSum)
+		a.IntersectInto(dst, []*ZSet[string]{b}, nil, AggregateSum)
+		assert.True(t, expected.Equal(dst))
+		assert.Equal(t, uint64(3), a.Len())
+		assert.Equal(t, uint64(3), b.Len())
+	})
+
+	t.Run("destination is an input", func(t *testing.T) {
+		dst := newZSetOf(map[string]float64{"a": 1, "b": 2, "c": 3})
+		dst.IntersectInto(dst, []*ZSet[string]{b}, nil, AggregateSum)
+		assert.Equal(t, map[string]float64{"a": 11, "b": 22}, dst.ToMap())
+		assert.NoError(t, dst.Validate())
+
+		dst = newZSetOf(map[string]float64{"c": 1, "e": 2})
+		c.IntersectInto(dst, []*ZSet[string]{dst}, nil, AggregateMin)
+		assert.Equal(t, map[string]float64{"c": 1, "e": 2}, dst.ToMap())
+		assert.NoError(t, dst.Validate())
+	})
+
+	t.Run("empty intersection", func(t *testing.T) {
+		dst := newZSetOf(map[string]float64{"x": 1})
+		a.IntersectInto(dst, []*ZSet[string]{c, newZSetOf(map[string]float64{"y": 1})}, nil, AggregateSum)
+		assert.Equal(t, uint64(0), dst.Len())
+		assert.NoError(t, dst.Validate())
+	})
+
+	t.Run("mismatched weights panic", func(t *testing.T) {
+		assert.Panics(t, func() {
+			a.IntersectInto(NewZSet(), []*ZSet[string]{b}, []float64{1}, AggregateSum)
+		})
+	})
+}
+
+func TestIntersectCard(t *testing.T) {
+	tests := []struct {
+		name     string
+		sets     []map[string]float64
+		limit    int64
+		expected int64
+	}{
+		{
+			name: "full intersection",
+			sets: []map[string]float64{
+				{"a": 1, "b": 2, "c": 3, "d": 4},
+				{"a": 10, "b": 20, "c": 30, "e": 50},
+				{"a": 100, "b": 200, "c": 300},
+			},
+			expected: 3,
+		},
+		{
+			name: "stops at limit",
+			sets: []map[string]float64{
+				{"a": 1, "b": 2, "c": 3, "d": 4},
+				{"a": 1, "b": 2, "c": 3, "d": 4},
+			},
+			limit:    2,
+			expected: 2,
+		},
+		{
+			name: "limit above cardinality",
+			sets: []map[string]float64{
+				{"a": 1, "b": 2},
+				{"a": 1, "b": 2, "c": 3},
+			},
+			limit:    10,
+			expected: 2,
+		},
+		{
+			name: "negative limit means no cap",
+			sets: []map[string]float64{
+				{"a": 1, "b": 2},
+				{"a": 1, "b": 2},
+			},
+			limit:    -1,
+			expected: 2,
+		},
+		{
+			name: "disjoint",
+			sets: []map[string]float64{
+				{"a": 1, "b": 2},
+				{"c": 1, "d": 2},
+			},
+			expected: 0,
+		},
+		{
+			name:     "single set",
+			sets:     []map[string]float64{{"a": 1, "b": 2}},
+			expected: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sets []*ZSet[string]
+			for _, m := range tt.sets {
+				sets = append(sets, newZSetOf(m))
+			}
+			assert.Equal(t, tt.expected, IntersectCard(sets, tt.limit))
+			if tt.limit <= 0 {
+				assert.Equal(t, int64(Intersect(sets, nil, AggregateSum).Len()), IntersectCard(sets, tt.limit))
+			}
+		})
+	}
+
+	t.Run("no sets", func(t *testing.T) {
+		assert.Equal(t, int64(0), IntersectCard[string](nil, 0))
+	})
+
+	t.Run("same set twice", func(t *testing.T) {
+		z := newZSetOf(map[string]float64{"a": 1, "b": 2})
+		assert.Equal(t, int64(2), IntersectCard([]*ZSet[string]{z, z}, 0))
+	})
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        map[string]float64
+		others   []map[string]float64
+		expected map[string]float64
+	}{
+		{
+			name:     "no subtrahends",
+			a:        map[string]float64{"a": 1, "b": 2},
+			expected: map[string]float64{"a": 1, "b": 2},
+		},
+		{
+			name:     "multiple subtrahends",
+			a:        map[string]float64{"a": 1, "b": 2, "c": 3, "d": 4},
+			others:   []map[string]float64{{"a": 100}, {"c": 0, "x": 9}},
+			expected: map[string]float64{"b": 2, "d": 4},
+		},
+		{
+			name:     "full removal",
+			a:        map[string]float64{"a": 1, "b": 2},
+			others:   []map[string]float64{{"a": 1}, {"b": 1}},
+			expected: map[string]float64{},
+		},
+		{
+			name:     "no overlap",
+			a:        map[string]float64{"a": 1, "b": 2},
+			others:   []map[string]float64{{"c": 1}},
+			expected: map[string]float64{"a": 1, "b": 2},
+		},
+		{
+			name:     "empty minuend",
+			a:        map[string]float64{},
+			others:   []map[string]float64{{"c": 1}},
+			expected: map[string]float64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newZSetOf(tt.a)
+			var others []*ZSet[string]
+			for _, m := range tt.others {
+				others = append(others, newZSetOf(m))
+			}
+			result := Diff(a, others...)
+			assert.Equal(t, tt.expected, result.dict)
+			assert.Equal(t, uint64(len(tt.expected)), result.Len())
+			assert.Equal(t, uint64(len(tt.a)), a.Len())
+		})
+	}
+
+	t.Run("diff with itself", func(t *testing.T) {
+		z := newZSetOf(map[string]float64{"a": 1})
+		assert.Equal(t, uint64(0), Diff(z, z).Len())
+	})
+}
@@ -0,0 +1,132 @@
+package zset
+
+import "sort"
+
+// Snapshot 是 ZSet 在某一时刻的只读视图，由 ZSet.Snapshot 创建。
+// 内部为按 (score, ele) 升序排列的切片和元素到位置的索引，创建后不再改变，
+// 可以在多个 goroutine 中无锁并发查询，且不受原集合之后修改的影响。
+type Snapshot[M comparable] struct {
+	entries []struct {
+		Member M
+		Score  float64
+	}
+	index map[M]int // 元素到 entries 下标（即升序排名）的映射
+}
+
+// Snapshot 创建集合当前内容的只读快照，复制全部元素，O(n)。
+// 返回与集合分离的快照，之后对集合的修改不会反映到快照中。
+func (z *ZSet[M]) Snapshot() *Snapshot[M] {
+	z.rlock()
+	defer z.runlock()
+
+	s := &Snapshot[M]{
+		entries: make([]struct {
+			Member M
+			Score  float64
+		}, 0, z.zsl.length),
+		index: make(map[M]int, z.zsl.length),
+	}
+	for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		s.index[x.ele] = len(s.entries)
+		s.entries = append(s.entries, struct {
+			Member M
+			Score  float64
+		}{
+			Member: x.ele,
+			Score:  x.score,
+		})
+	}
+	return s
+}
+
+// Len 获取快照中元素的数量。
+func (s *Snapshot[M]) Len() uint64 {
+	return uint64(len(s.entries))
+}
+
+// Score 获取快照中指定元素的分数。
+// ele: 要获取分数的元素。
+// 返回元素的分数和元素是否存在的标志。
+func (s *Snapshot[M]) Score(ele M) (float64, bool) {
+	i, exists := s.index[ele]
+	if !exists {
+		return 0, false
+	}
+	return s.entries[i].Score, true
+}
+
+// Rank 获取快照中指定元素的排名，O(1)。
+// ele: 要获取排名的元素。
+// reverse: 是否按降序排名。
+// 返回元素的排名（从 0 开始），如果元素不存在返回 -1。
+func (s *Snapshot[M]) Rank(ele M, reverse bool) int64 {
+	i, exists := s.index[ele]
+	if !exists {
+		return -1
+	}
+	if reverse {
+		return int64(len(s.entries) - 1 - i)
+	}
+	return int64(i)
+}
+
+// GetByRank 获取快照中指定排名的元素，O(1)。
+// rank: 要获取的排名（从 0 开始），负数表示从末尾倒数，语义同 ZSet.GetByRank。
+// reverse: 是否按降序排名。
+// 返回元素、元素的分数和元素是否存在的标志，排名超出 [-Len(), Len()) 时返回 false。
+func (s *Snapshot[M]) GetByRank(rank int64, reverse bool) (M, float64, bool) {
+	rank, ok := normalizeRank(rank, uint64(len(s.entries)))
+	if !ok {
+		var zero M
+		return zero, 0, false
+	}
+	if reverse {
+		rank = int64(len(s.entries)) - 1 - rank
+	}
+	e := s.entries[rank]
+	return e.Member, e.Score, true
+}
+
+// RangeByScore 按分数范围获取快照中的元素，语义与 ZSet.RangeByScore 相同，通过二分查找定位，O(log n + m)。
+// min: 分数范围的最小值（包含）。
+// max: 分数范围的最大值（包含）。
+// offset: 跳过的元素数量。
+// count: 要获取的元素数量，-1 表示获取所有符合条件的元素。
+// 返回符合条件的元素列表，min 大于 max 时返回 nil。
+func (s *Snapshot[M]) RangeByScore(min, max float64, offset, count int64) []struct {
+	Member M
+	Score  float64
+} {
+	var result []struct {
+		Member M
+		Score  float64
+	}
+	if !(min <= max) {
+		return result
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	lo := sort.Search(len(s.entries), func(i int) bool { return s.entries[i].Score >= min })
+	hi := sort.Search(len(s.entries), func(i int) bool { return s.entries[i].Score > max })
+	start := hi
+	if offset < int64(hi-lo) {
+		start = lo + int(offset)
+	}
+	end := hi
+	if count >= 0 && int64(end-start) > count {
+		end = start + int(count)
+	}
+	if start >= end {
+		return result
+	}
+
+	// 复制结果，避免调用方修改快照内部数据
+	result = make([]struct {
+		Member M
+		Score  float64
+	}, end-start)
+	copy(result, s.entries[start:end])
+	return result
+}
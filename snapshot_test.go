@@ -0,0 +1,122 @@
+package zset
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZSet_Snapshot(t *testing.T) {
+	z := newZSetOf(map[string]float64{"a": 1, "b": 2, "c": 2, "d": 3, "e": 5})
+	snap := z.Snapshot()
+
+	// 修改原集合
+	z.Add("a", 10)
+	z.Remove("c")
+	z.Add("f", 0)
+	z.Clear()
+	z.Add("x", 1)
+
+	assert.Equal(t, uint64(5), snap.Len())
+
+	score, ok := snap.Score("a")
+	assert.True(t, ok)
+	assert.Equal(t, float64(1), score)
+	_, ok = snap.Score("x")
+	assert.False(t, ok)
+
+	assert.Equal(t, int64(0), snap.Rank("a", false))
+	assert.Equal(t, int64(2), snap.Rank("c", false))
+	assert.Equal(t, int64(0), snap.Rank("e", true))
+	assert.Equal(t, int64(-1), snap.Rank("x", false))
+
+	member, score, ok := snap.GetByRank(1, false)
+	assert.True(t, ok)
+	assert.Equal(t, "b", member)
+	assert.Equal(t, float64(2), score)
+	member, _, _ = snap.GetByRank(0, true)
+	assert.Equal(t, "e", member)
+	_, _, ok = snap.GetByRank(5, false)
+	assert.False(t, ok)
+
+	// 负数排名从末尾倒数，与 ZSet.GetByRank 一致
+	orig := newZSetOf(map[string]float64{"a": 1, "b": 2, "c": 2, "d": 3, "e": 5})
+	for _, rank := range []int64{-1, -2, -5} {
+		for _, reverse := range []bool{false, true} {
+			member, score, ok := snap.GetByRank(rank, reverse)
+			assert.True(t, ok)
+			expectedMember, expectedScore, _ := orig.GetByRank(rank, reverse)
+			assert.Equal(t, expectedMember, member)
+			assert.Equal(t, expectedScore, score)
+		}
+	}
+	member, _, _ = snap.GetByRank(-1, false)
+	assert.Equal(t, "e", member)
+	member, _, _ = snap.GetByRank(-5, false)
+	assert.Equal(t, "a", member)
+	member, _, _ = snap.GetByRank(-1, true)
+	assert.Equal(t, "a", member)
+	_, _, ok = snap.GetByRank(-6, false)
+	assert.False(t, ok)
+}
+
+func TestSnapshot_RangeByScore(t *testing.T) {
+	z := newZSetOf(map[string]float64{"a": 1, "b": 2, "c": 2, "d": 3, "e": 5})
+	snap := z.Snapshot()
+
+	tests := []struct {
+		name   string
+		min    float64
+		max    float64
+		offset int64
+		count  int64
+	}{
+		{"all", math.Inf(-1), math.Inf(1), 0, -1},
+		{"inner", 2, 3, 0, -1},
+		{"between scores", 1.5, 4, 0, -1},
+		{"offset", 2, 5, 1, -1},
+		{"offset and count", 1, 5, 1, 2},
+		{"offset past range", 2, 3, 10, -1},
+		{"zero count", 1, 5, 0, 0},
+		{"empty", 3.5, 4.5, 0, -1},
+		{"inverted", 5, 1, 0, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, z.RangeByScore(tt.min, tt.max, tt.offset, tt.count), snap.RangeByScore(tt.min, tt.max, tt.offset, tt.count))
+		})
+	}
+
+	// 修改返回结果不影响快照
+	result := snap.RangeByScore(1, 1, 0, -1)
+	result[0].Score = 100
+	score, _ := snap.Score("a")
+	assert.Equal(t, float64(1), score)
+}
+
+func TestSnapshot_ConcurrentReads(t *testing.T) {
+	z := NewSyncZSet()
+	for i := 0; i < 100; i++ {
+		z.Add("member"+strconv.Itoa(i), float64(i))
+	}
+	snap := z.Snapshot()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				snap.RangeByScore(10, 50, 0, 10)
+				snap.GetByRank(int64(i%100), i%2 == 0)
+				z.Add("live", float64(i))
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, uint64(100), snap.Len())
+}
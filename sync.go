@@ -0,0 +1,125 @@
+package zset
+
+import (
+	"sort"
+	"sync"
+	"unsafe"
+)
+
+// NewSyncZSet 创建一个并发安全的有序集合 ZSet。
+// 返回的 ZSet 内部持有读写锁：只读方法获取读锁，修改方法获取写锁，可以在多个 goroutine 间共享。
+// 普通的 NewZSet 创建的集合不加锁，没有额外开销。
+func NewSyncZSet() *ZSet[string] {
+	z := NewZSet()
+	z.mu = &sync.RWMutex{}
+	return z
+}
+
+// NewSyncZSetFunc 创建一个元素类型为 M 的并发安全有序集合 ZSet。
+// less: 分数相同时元素的排序规则，要求同 NewZSetFunc。
+// 返回的 ZSet 内部持有读写锁，加锁方式同 NewSyncZSet。
+func NewSyncZSetFunc[M comparable](less func(a, b M) bool) *ZSet[M] {
+	z := NewZSetFunc(less)
+	z.mu = &sync.RWMutex{}
+	return z
+}
+
+// lock 获取写锁，非并发安全模式下不做任何操作。
+func (z *ZSet[M]) lock() {
+	if z.mu != nil {
+		z.mu.Lock()
+	}
+}
+
+// unlock 释放写锁。
+func (z *ZSet[M]) unlock() {
+	if z.mu != nil {
+		z.mu.Unlock()
+	}
+}
+
+// rlock 获取读锁，非并发安全模式下不做任何操作。
+func (z *ZSet[M]) rlock() {
+	if z.mu != nil {
+		z.mu.RLock()
+	}
+}
+
+// runlock 释放读锁。
+func (z *ZSet[M]) runlock() {
+	if z.mu != nil {
+		z.mu.RUnlock()
+	}
+}
+
+// sortedSets 对集合去重并按地址排序，保证同时锁定多个集合时的加锁顺序一致，避免死锁。
+// sets: 要锁定的集合。
+// 返回去重排序后的集合列表。
+func sortedSets[M comparable](sets []*ZSet[M]) []*ZSet[M] {
+	ordered := make([]*ZSet[M], 0, len(sets))
+	seen := make(map[*ZSet[M]]bool, len(sets))
+	for _, z := range sets {
+		if z != nil && !seen[z] {
+			seen[z] = true
+			ordered = append(ordered, z)
+		}
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return uintptr(unsafe.Pointer(ordered[i])) < uintptr(unsafe.Pointer(ordered[j]))
+	})
+	return ordered
+}
+
+// rlockAll 按一致的顺序获取多个集合的读锁。
+// sets: 要锁定的集合，允许重复。
+// 返回释放所有读锁的函数。
+func rlockAll[M comparable](sets ...*ZSet[M]) func() {
+	ordered := sortedSets(sets)
+	for _, z := range ordered {
+		z.rlock()
+	}
+	return func() {
+		for i := len(ordered) - 1; i >= 0; i-- {
+			ordered[i].runlock()
+		}
+	}
+}
+
+// lockAll 按一致的顺序获取多个集合的写锁。
+// sets: 要修改的集合，允许重复。
+// 返回释放所有写锁的函数。
+func lockAll[M comparable](sets ...*ZSet[M]) func() {
+	ordered := sortedSets(sets)
+	for _, z := range ordered {
+		z.lock()
+	}
+	return func() {
+		for i := len(ordered) - 1; i >= 0; i-- {
+			ordered[i].unlock()
+		}
+	}
+}
+
+// lockInto 按一致的顺序获取 dst 的写锁和 srcs 的读锁，srcs 中与 dst 相同的集合只获取写锁。
+// dst: 要修改的集合。
+// srcs: 只读取的集合，允许重复。
+// 返回释放所有锁的函数。
+func lockInto[M comparable](dst *ZSet[M], srcs ...*ZSet[M]) func() {
+	ordered := sortedSets(append([]*ZSet[M]{dst}, srcs...))
+	for _, z := range ordered {
+		if z == dst {
+			z.lock()
+		} else {
+			z.rlock()
+		}
+	}
+	return func() {
+		for i := len(ordered) - 1; i >= 0; i-- {
+			if ordered[i] == dst {
+				ordered[i].unlock()
+			} else {
+				ordered[i].runlock()
+			}
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package zset
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSyncZSet(t *testing.T) {
+	z := NewSyncZSet()
+	assert.NotNil(t, z.mu)
+	assert.True(t, z.Add("a", 1))
+	assert.Equal(t, int64(0), z.Rank("a", false))
+	assert.Nil(t, NewZSet().mu)
+}
+
+func TestNewSyncZSetFunc(t *testing.T) {
+	z := NewSyncZSetFunc(func(a, b int64) bool { return a < b })
+	assert.NotNil(t, z.mu)
+	assert.Nil(t, NewZSetFunc(func(a, b int64) bool { return a < b }).mu)
+
+	const goroutines = 8
+	const ops = 500
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < ops; i++ {
+				ele := int64((g*ops + i) % 100)
+				switch i % 3 {
+				case 0:
+					z.Add(ele, float64(i))
+				case 1:
+					z.Remove(ele)
+				case 2:
+					z.Rank(ele, false)
+					z.RangeByScore(0, float64(ops), 0, 10)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	assert.NoError(t, z.Validate())
+	// 克隆保留并发安全模式
+	assert.NotNil(t, z.Clone().mu)
+}
+
+func TestSyncZSet_Concurrent(t *testing.T) {
+	z := NewSyncZSet()
+	other := NewSyncZSet()
+
+	const goroutines = 16
+	const ops = 500
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < ops; i++ {
+				ele := "member" + strconv.Itoa((g*ops+i)%100)
+				switch i % 4 {
+				case 0:
+					z.Add(ele, float64(i))
+				case 1:
+					z.Remove(ele)
+				case 2:
+					z.Rank(ele, i%2 == 0)
+					z.Score(ele)
+				case 3:
+					z.RangeByScore(0, float64(ops), 0, 10)
+					z.GetByRank(0, true)
+					ChangedRanks(z, other)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	// 哈希表和跳跃表保持一致
+	assert.Equal(t, uint64(len(z.dict)), z.Len())
+	for ele := range z.dict {
+		assert.NotEqual(t, int64(-1), z.Rank(ele, false))
+	}
+}
+
+func TestSyncZSet_ConcurrentSets(t *testing.T) {
+	sets := []*ZSet[string]{NewSyncZSet(), NewSyncZSet(), NewSyncZSet()}
+
+	var wg sync.WaitGroup
+	for _, z := range sets {
+		wg.Add(1)
+		go func(z *ZSet[string]) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				z.Add("member"+strconv.Itoa(i), float64(i))
+			}
+		}(z)
+	}
+	wg.Wait()
+
+	for _, z := range sets {
+		assert.Equal(t, uint64(1000), z.Len())
+	}
+}
+
+func TestSyncZSet_ConcurrentSubtract(t *testing.T) {
+	a, b := NewSyncZSet(), NewSyncZSet()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				ele := "member" + strconv.Itoa(i%50)
+				if g%2 == 0 {
+					a.Add(ele, float64(i))
+					a.SubtractSet(b)
+				} else {
+					b.Add(ele, float64(i))
+					b.SubtractSet(a)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	assert.NoError(t, a.Validate())
+	assert.NoError(t, b.Validate())
+}
+
+func TestSyncZSet_ConcurrentMoveTo(t *testing.T) {
+	a, b := NewSyncZSet(), NewSyncZSet()
+	for i := 0; i < 50; i++ {
+		a.Add("member"+strconv.Itoa(i), float64(i))
+	}
+
+	// 两个方向同时移动，按地址顺序加锁不会死锁，元素总数保持不变
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				ele := "member" + strconv.Itoa(i%50)
+				if g%2 == 0 {
+					a.MoveTo(b, ele)
+				} else {
+					b.MoveTo(a, ele)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	assert.Equal(t, uint64(50), a.Len()+b.Len())
+	assert.NoError(t, a.Validate())
+	assert.NoError(t, b.Validate())
+}
+
+func TestSyncZSet_ConcurrentReplaceAll(t *testing.T) {
+	z := NewSyncZSet()
+	batches := make([]map[string]float64, 2)
+	for b := range batches {
+		batches[b] = make(map[string]float64)
+		for i := 0; i < 100; i++ {
+			batches[b]["batch"+strconv.Itoa(b)+"-"+strconv.Itoa(i)] = float64(i)
+		}
+	}
+	z.ReplaceAll(batches[0])
+
+	// 读者只能看到某一批完整的内容，不会看到两批混合或半途的状态
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			z.ReplaceAll(batches[i%2])
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			members := z.Members(false)
+			if !assert.Len(t, members, 100) {
+				return
+			}
+			prefix := members[0][:6]
+			for _, m := range members {
+				assert.Equal(t, prefix, m[:6])
+			}
+		}
+	}()
+	wg.Wait()
+
+	assert.NoError(t, z.Validate())
+}
+
+func TestSyncZSet_ConcurrentIntersectInto(t *testing.T) {
+	a, b, dst := NewSyncZSet(), NewSyncZSet(), NewSyncZSet()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 300; i++ {
+				ele := "member" + strconv.Itoa(i%50)
+				switch g {
+				case 0:
+					a.Add(ele, float64(i))
+				case 1:
+					b.Add(ele, float64(i))
+				case 2:
+					a.IntersectInto(dst, []*ZSet[string]{b}, nil, AggregateSum)
+				case 3:
+					b.IntersectInto(a, []*ZSet[string]{dst}, nil, AggregateMax)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	assert.NoError(t, a.Validate())
+	assert.NoError(t, b.Validate())
+	assert.NoError(t, dst.Validate())
+}
@@ -0,0 +1,116 @@
+package zset
+
+import (
+	"fmt"
+	"math"
+)
+
+// Validate 检查集合内部结构的一致性，主要用于测试和排查数据损坏。
+// 依次检查：跳跃表长度与哈希表大小一致、节点与哈希表中的分数一致、节点按 (score, ele) 严格有序、
+// 后向指针与尾指针正确、各层前向指针的跨度与实际排名差一致、当前层级与节点层级相符、载荷只属于现存元素。
+// 返回发现的第一处不一致的描述，结构正确时返回 nil。
+func (z *ZSet[M]) Validate() error {
+	z.rlock()
+	defer z.runlock()
+
+	return z.checkInvariants()
+}
+
+// Stats 获取集合内部结构的统计信息，用于容量规划和运行时排查，开销为 O(1)。
+// 返回跳跃表的长度、跳跃表当前的最高层级和哈希表的大小；结构正常时 length 与 dictLen 相等。
+func (z *ZSet[M]) Stats() (length uint64, level int, dictLen int) {
+	z.rlock()
+	defer z.runlock()
+
+	return z.zsl.length, z.zsl.level, len(z.dict)
+}
+
+// checkInvariants 检查集合内部结构的一致性，调用方需持有读锁。
+func (z *ZSet[M]) checkInvariants() error {
+	sl := z.zsl
+	if sl.length != uint64(len(z.dict)) {
+		return fmt.Errorf("zset: skiplist length %d != dict size %d", sl.length, len(z.dict))
+	}
+	if sl.level < 1 || sl.level > sl.maxLevel {
+		return fmt.Errorf("zset: level %d out of range [1, %d]", sl.level, sl.maxLevel)
+	}
+	for ele := range z.payloads {
+		if _, exists := z.dict[ele]; !exists {
+			return fmt.Errorf("zset: payload for missing member %v", ele)
+		}
+	}
+
+	// 沿第 0 层检查顺序、分数和后向指针，同时记录每个节点的排名
+	ranks := make(map[*skiplistNode[M]]uint64, sl.length)
+	var prev *skiplistNode[M]
+	maxNodeLevel := 1
+	var rank uint64 = 0
+	for x := sl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		rank++
+		if rank > sl.length {
+			return fmt.Errorf("zset: more than %d nodes in level 0", sl.length)
+		}
+		ranks[x] = rank
+
+		if math.IsNaN(x.score) {
+			return fmt.Errorf("zset: NaN score at rank %d", rank)
+		}
+		score, exists := z.dict[x.ele]
+		if !exists {
+			return fmt.Errorf("zset: node at rank %d missing from dict", rank)
+		}
+		if score != x.score {
+			return fmt.Errorf("zset: node at rank %d has score %v, dict has %v", rank, x.score, score)
+		}
+		if x.backward != prev {
+			return fmt.Errorf("zset: node at rank %d has wrong backward pointer", rank)
+		}
+		if prev != nil && !(prev.score < x.score || (prev.score == x.score && sl.less(prev.ele, x.ele))) {
+			return fmt.Errorf("zset: node at rank %d is not ordered after rank %d", rank, rank-1)
+		}
+		if len(x.level) < 1 || len(x.level) > sl.maxLevel {
+			return fmt.Errorf("zset: node at rank %d has level %d out of range [1, %d]", rank, len(x.level), sl.maxLevel)
+		}
+		maxNodeLevel = max(maxNodeLevel, len(x.level))
+		prev = x
+	}
+	if rank != sl.length {
+		return fmt.Errorf("zset: level 0 has %d nodes, length is %d", rank, sl.length)
+	}
+	if sl.tail != prev {
+		return fmt.Errorf("zset: tail does not point to the last node")
+	}
+	if maxNodeLevel != sl.level && sl.length > 0 {
+		return fmt.Errorf("zset: level is %d, highest node level is %d", sl.level, maxNodeLevel)
+	}
+
+	// 检查各层的前向指针和跨度
+	for i := 0; i < sl.level; i++ {
+		var from uint64 = 0
+		x := sl.header
+		for ; x.level[i].forward != nil; x = x.level[i].forward {
+			next := x.level[i].forward
+			to, exists := ranks[next]
+			if !exists {
+				return fmt.Errorf("zset: level %d links to a node not in level 0", i)
+			}
+			if len(next.level) <= i {
+				return fmt.Errorf("zset: level %d links to node at rank %d with level %d", i, to, len(next.level))
+			}
+			if to <= from || x.level[i].span != to-from {
+				return fmt.Errorf("zset: level %d span from rank %d is %d, want %d", i, from, x.level[i].span, to-from)
+			}
+			from = to
+		}
+		// 每层最后一个节点的跨度等于其后剩余的节点数量，删除节点时会依赖这一点递减
+		if x.level[i].span != sl.length-from {
+			return fmt.Errorf("zset: level %d trailing span from rank %d is %d, want %d", i, from, x.level[i].span, sl.length-from)
+		}
+	}
+	for i := sl.level; i < len(sl.header.level); i++ {
+		if sl.header.level[i].forward != nil {
+			return fmt.Errorf("zset: header level %d above current level %d is linked", i, sl.level)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package zset
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZSet_Validate(t *testing.T) {
+	assert.NoError(t, NewZSet().Validate())
+
+	t.Run("random operations", func(t *testing.T) {
+		r := rand.New(rand.NewSource(1))
+		z := NewZSetWithSource(rand.NewSource(2))
+		for i := 0; i < 5000; i++ {
+			ele := strconv.Itoa(r.Intn(500))
+			switch r.Intn(4) {
+			case 0, 1:
+				z.Add(ele, float64(r.Intn(50)))
+			case 2:
+				z.Remove(ele)
+			case 3:
+				z.IncrBy(ele, float64(r.Intn(10)-5))
+			}
+			if i%250 == 0 {
+				if !assert.NoError(t, z.Validate(), "after operation %d", i) {
+					return
+				}
+			}
+		}
+		assert.NoError(t, z.Validate())
+
+		z.RemoveRangeByRank(10, 50)
+		assert.NoError(t, z.Validate())
+		z.RemoveRangeByScore(10, 20)
+		assert.NoError(t, z.Validate())
+		z.PopMinN(5)
+		assert.NoError(t, z.Validate())
+	})
+
+	t.Run("detects corruption", func(t *testing.T) {
+		build := func() *ZSet[string] {
+			z := NewZSet()
+			for i := 0; i < 50; i++ {
+				z.Add(strconv.Itoa(i), float64(i))
+			}
+			return z
+		}
+
+		z := build()
+		z.dict["extra"] = 1
+		assert.ErrorContains(t, z.Validate(), "dict size")
+
+		z = build()
+		z.dict["3"] = 100
+		assert.ErrorContains(t, z.Validate(), "dict has")
+
+		z = build()
+		z.zsl.header.level[0].forward.score = 1000
+		assert.Error(t, z.Validate())
+
+		z = build()
+		z.zsl.tail = z.zsl.tail.backward
+		assert.ErrorContains(t, z.Validate(), "tail")
+
+		z = build()
+		z.zsl.header.level[0].forward.level[0].forward.backward = nil
+		assert.ErrorContains(t, z.Validate(), "backward")
+
+		z = build()
+		z.zsl.header.level[0].span = 2
+		assert.ErrorContains(t, z.Validate(), "span")
+
+		z = build()
+		z.zsl.tail.level[0].span = 1
+		assert.ErrorContains(t, z.Validate(), "trailing span")
+	})
+}
+
+func TestZSet_DeleteSpans(t *testing.T) {
+	t.Run("pathological sequence", func(t *testing.T) {
+		// 分数全部相同、从两端反复插入删除、清空后重建，每一步之后检查所有层的跨度
+		z := NewZSetWithSource(rand.NewSource(3))
+		check := func(step string) bool {
+			return assert.NoError(t, z.Validate(), step)
+		}
+		for round := 0; round < 20; round++ {
+			for i := 0; i < 64; i++ {
+				z.Add("a"+strconv.Itoa(i), 0)
+				z.Add("z"+strconv.Itoa(i), 0)
+				if !check("add") {
+					return
+				}
+			}
+			for i := 0; i < 32; i++ {
+				z.PopMin()
+				z.PopMax()
+				if !check("pop") {
+					return
+				}
+			}
+			for i := 0; i < 16; i++ {
+				z.Remove("a" + strconv.Itoa(32+i))
+				z.Add("a"+strconv.Itoa(32+i), float64(round))
+				if !check("re-add") {
+					return
+				}
+			}
+			z.RemoveRangeByRank(1, -2)
+			if !check("remove range") {
+				return
+			}
+			if round%5 == 4 {
+				z.Clear()
+				if !check("clear") {
+					return
+				}
+			}
+		}
+		for z.Len() > 0 {
+			z.PopMax()
+			if !check("drain") {
+				return
+			}
+		}
+		_, level, _ := z.Stats()
+		assert.Equal(t, 1, level)
+	})
+
+	t.Run("zero span does not wrap", func(t *testing.T) {
+		if debugChecks {
+			t.Skip("zsetdebug build panics on corrupted spans")
+		}
+
+		z := NewZSetWithSource(rand.NewSource(1))
+		for i := 0; i < 50; i++ {
+			z.Add(strconv.Itoa(i), float64(i))
+		}
+		// 人为破坏：最高层最后一个节点的跨度清零后删除其后的节点
+		top := z.zsl.level - 1
+		x := z.zsl.header
+		for x.level[top].forward != nil {
+			x = x.level[top].forward
+		}
+		if !assert.NotSame(t, z.zsl.tail, x) {
+			return
+		}
+		x.level[top].span = 0
+		z.Remove(z.zsl.tail.ele)
+
+		assert.Equal(t, uint64(0), x.level[top].span)
+		assert.Error(t, z.Validate())
+	})
+}
+
+func TestZSet_Stats(t *testing.T) {
+	z := NewZSetWithSource(rand.NewSource(1))
+	length, level, dictLen := z.Stats()
+	assert.Equal(t, uint64(0), length)
+	assert.Equal(t, 1, level)
+	assert.Equal(t, 0, dictLen)
+
+	for i := 0; i < 1000; i++ {
+		z.Add(strconv.Itoa(i), float64(i))
+	}
+	for i := 0; i < 1000; i += 4 {
+		z.Remove(strconv.Itoa(i))
+	}
+	z.Add("1", 5000) // 更新分数不改变数量
+
+	length, level, dictLen = z.Stats()
+	assert.Equal(t, uint64(750), length)
+	assert.Equal(t, 750, dictLen)
+	assert.GreaterOrEqual(t, level, 2)
+	assert.LessOrEqual(t, level, SKIPLIST_MAXLEVEL)
+
+	// 删除全部元素后层级回落
+	z.RemoveRangeByRank(0, -1)
+	length, level, dictLen = z.Stats()
+	assert.Equal(t, uint64(0), length)
+	assert.Equal(t, 1, level)
+	assert.Equal(t, 0, dictLen)
+
+	// 两个结构不一致时可以从统计信息中发现
+	z.Add("a", 1)
+	z.dict["b"] = 2
+	length, _, dictLen = z.Stats()
+	assert.NotEqual(t, int(length), dictLen)
+}
+
+func TestZSet_RankCorruptedLength(t *testing.T) {
+	build := func() *ZSet[string] {
+		z := NewZSet()
+		for i := 0; i < 10; i++ {
+			z.Add(strconv.Itoa(i), float64(i))
+		}
+		return z
+	}
+
+	// 长度小于实际节点数量时，靠后元素的排名超过长度，降序换算会下溢
+	z := build()
+	z.zsl.length = 5
+	assert.Equal(t, int64(-1), z.Rank("9", true))
+	assert.Equal(t, int64(-1), z.Rank("9", false))
+	rank, _, ok := z.RankWithScore("9", true)
+	assert.False(t, ok)
+	assert.Equal(t, int64(-1), rank)
+	assert.Equal(t, int64(0), z.Rank("4", true))
+	assert.ErrorContains(t, z.Validate(), "length")
+
+	// 跨度被放大时同样返回 -1
+	z = build()
+	for i := 0; i < z.zsl.level; i++ {
+		z.zsl.header.level[i].span += 100
+	}
+	assert.Equal(t, int64(-1), z.Rank("0", true))
+	assert.ErrorContains(t, z.Validate(), "span")
+
+	// 结构正确时所有降序排名都在 [0, Len()) 内
+	z = build()
+	assert.NoError(t, z.Validate())
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, int64(9-i), z.Rank(strconv.Itoa(i), true))
+	}
+}
@@ -1,70 +1,183 @@
 package zset
 
 import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"math"
 	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unsafe"
 )
 
-// SKIPLIST_MAXLEVEL 定义跳跃表的最大层数。
+// SKIPLIST_MAXLEVEL 定义跳跃表默认的最大层数，可通过 NewZSetWithConfig 为单个集合指定。
 const SKIPLIST_MAXLEVEL = 32
 
-// SKIPLIST_P 定义跳跃表节点增加层级的概率。
+// maxLevelLimit 是单个集合允许的最大层级上限，修改跳跃表时的查找路径使用该长度的定长数组，避免堆分配。
+const maxLevelLimit = 64
+
+// SKIPLIST_P 定义跳跃表节点增加层级的默认概率，可通过 NewZSetWithConfig 为单个集合指定。
 const SKIPLIST_P = 0.25
 
 // 跳跃表节点
-type skiplistNode struct {
-	ele      string          // 元素值
-	score    float64         // 分数
-	backward *skiplistNode   // 后向指针
-	level    []skiplistLevel // 层级数组
+type skiplistNode[M comparable] struct {
+	ele      M                  // 元素值
+	score    float64            // 分数
+	backward *skiplistNode[M]   // 后向指针
+	level    []skiplistLevel[M] // 层级数组
 }
 
 // 跳跃表层级
-type skiplistLevel struct {
-	forward *skiplistNode // 前向指针
-	span    uint64        // 跨度
+type skiplistLevel[M comparable] struct {
+	forward *skiplistNode[M] // 前向指针
+	span    uint64           // 跨度
 }
 
 // 跳跃表
-type skiplist struct {
-	header *skiplistNode // 头节点
-	tail   *skiplistNode // 尾节点
-	length uint64        // 节点数量
-	level  int           // 当前最大层级
+type skiplist[M comparable] struct {
+	header   *skiplistNode[M]     // 头节点
+	tail     *skiplistNode[M]     // 尾节点
+	length   uint64               // 节点数量
+	level    int                  // 当前最大层级
+	maxLevel int                  // 允许的最大层级
+	p        float64              // 节点增加一层的概率
+	less     func(a, b M) bool    // 分数相同时元素的排序规则
+	rand     *rand.Rand           // 生成层级的随机数源，为 nil 时使用包级共享的 rng
+	free     [][]*skiplistNode[M] // 按层级缓存的已删除节点，free[i] 中节点的层级为 i+1
 }
 
 // ZSet 有序集合，结合哈希表和跳跃表实现。
-type ZSet struct {
-	dict map[string]float64 // 哈希表，映射元素到分数
-	zsl  *skiplist          // 跳跃表，按分数排序元素
+// M 为元素类型，分数固定为 float64；分数相同的元素按创建集合时指定的 less 排序。
+type ZSet[M comparable] struct {
+	dict     map[M]float64 // 哈希表，映射元素到分数
+	zsl      *skiplist[M]  // 跳跃表，按分数排序元素
+	mu       *sync.RWMutex // 读写锁，仅并发安全模式下非空
+	payloads map[M]any     // 元素载荷，只保存设置过非 nil 载荷的元素，未使用载荷的集合为 nil
 }
 
-// 初始化随机数生成器
+// rng 是包级私有的随机数生成器，不读取也不修改 math/rand 的全局状态
 var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
 
+// rngMu 保护 rng，不同集合可能在多个 goroutine 中同时插入
+var rngMu sync.Mutex
+
+// randInt63n 返回 [0, n) 内均匀分布的随机整数。
+func randInt63n(n int64) int64 {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+
+	return rng.Int63n(n)
+}
+
+// randFloat64 使用包级 rng 生成 [0, 1) 内的随机浮点数。
+func randFloat64() float64 {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+
+	return rng.Float64()
+}
+
 // createNode 创建一个新的跳跃表节点。
 // level: 节点的层级。
 // score: 节点的分数。
 // ele: 节点的元素值。
 // 返回新创建的跳跃表节点指针。
-func createNode(level int, score float64, ele string) *skiplistNode {
-	node := &skiplistNode{
-		ele:   ele,
-		score: score,
-		level: make([]skiplistLevel, level),
+// 层级不超过 4 时节点与层级数组在一次分配中完成，按 1、2、4 层分级以避免浪费。
+// 层级数组的容量固定为节点层级，不会越界写入相邻内存。
+func createNode[M comparable](level int, score float64, ele M) *skiplistNode[M] {
+	var node *skiplistNode[M]
+	switch {
+	case level == 1:
+		n := &struct {
+			node   skiplistNode[M]
+			levels [1]skiplistLevel[M]
+		}{}
+		node = &n.node
+		node.level = n.levels[:]
+	case level == 2:
+		n := &struct {
+			node   skiplistNode[M]
+			levels [2]skiplistLevel[M]
+		}{}
+		node = &n.node
+		node.level = n.levels[:]
+	case level <= 4:
+		n := &struct {
+			node   skiplistNode[M]
+			levels [4]skiplistLevel[M]
+		}{}
+		node = &n.node
+		node.level = n.levels[:level:level]
+	default:
+		node = &skiplistNode[M]{level: make([]skiplistLevel[M], level)}
 	}
+	node.ele = ele
+	node.score = score
 	return node
 }
 
+// nodeFreeListMax 是跳跃表为每种层级缓存的空闲节点数量上限。
+const nodeFreeListMax = 256
+
+// newNode 创建一个跳跃表节点，优先复用已删除的同层级节点。
+// level: 节点的层级。
+// score: 节点的分数。
+// ele: 节点的元素值。
+// 返回可以直接插入跳跃表的节点指针。
+func (sl *skiplist[M]) newNode(level int, score float64, ele M) *skiplistNode[M] {
+	if level <= len(sl.free) {
+		if n := len(sl.free[level-1]); n > 0 {
+			x := sl.free[level-1][n-1]
+			sl.free[level-1][n-1] = nil
+			sl.free[level-1] = sl.free[level-1][:n-1]
+			x.ele = ele
+			x.score = score
+			return x
+		}
+	}
+	return createNode(level, score, ele)
+}
+
+// freeNode 将已从跳跃表摘除的节点重置后放入空闲列表，调用方之后不能再访问该节点。
+// x: 要回收的节点。
+func (sl *skiplist[M]) freeNode(x *skiplistNode[M]) {
+	// 清空所有引用，避免空闲节点持有元素或其他节点导致无法回收
+	var zero M
+	x.ele = zero
+	x.score = 0
+	x.backward = nil
+	clear(x.level)
+
+	if sl.free == nil {
+		sl.free = make([][]*skiplistNode[M], sl.maxLevel)
+	}
+	if level := len(x.level); level <= len(sl.free) && len(sl.free[level-1]) < nodeFreeListMax {
+		sl.free[level-1] = append(sl.free[level-1], x)
+	}
+}
+
 // createSkiplist 创建一个新的跳跃表。
+// maxLevel: 跳跃表允许的最大层级。
+// less: 分数相同时元素的排序规则。
 // 返回新创建的跳跃表指针。
-func createSkiplist() *skiplist {
-	sl := &skiplist{
-		level:  1,
-		length: 0,
+func createSkiplist[M comparable](maxLevel int, less func(a, b M) bool) *skiplist[M] {
+	sl := &skiplist[M]{
+		level:    1,
+		length:   0,
+		maxLevel: maxLevel,
+		p:        SKIPLIST_P,
+		less:     less,
 	}
-	sl.header = createNode(SKIPLIST_MAXLEVEL, 0, "")
-	for j := 0; j < SKIPLIST_MAXLEVEL; j++ {
+	var zero M
+	sl.header = createNode(maxLevel, 0, zero)
+	for j := 0; j < maxLevel; j++ {
 		sl.header.level[j].forward = nil
 		sl.header.level[j].span = 0
 	}
@@ -73,20 +186,135 @@ func createSkiplist() *skiplist {
 	return sl
 }
 
-// NewZSet 创建一个新的有序集合 ZSet。
+// NewZSet 创建一个新的有序集合 ZSet，元素为字符串，分数相同时按字典序排序。
+// 返回新创建的 ZSet 指针。
+func NewZSet() *ZSet[string] {
+	return NewZSetFunc(func(a, b string) bool { return a < b })
+}
+
+// NewZSetFunc 创建一个元素类型为 M 的有序集合 ZSet。
+// less: 分数相同时元素的排序规则，必须是严格弱序，且对不同元素给出确定的先后关系；
+// 为 nil 时使用元素类型的默认排序规则（字符串为字典序），没有默认规则的元素类型会 panic。
+// 返回新创建的 ZSet 指针。
+func NewZSetFunc[M comparable](less func(a, b M) bool) *ZSet[M] {
+	if less == nil {
+		var ok bool
+		if less, ok = defaultLess[M](); !ok {
+			panic("zset: NewZSetFunc requires a non-nil less for this member type")
+		}
+	}
+	return &ZSet[M]{
+		dict: make(map[M]float64),
+		zsl:  createSkiplist(SKIPLIST_MAXLEVEL, less),
+	}
+}
+
+// NewZSetWithComparator 创建一个元素为字符串、分数相同时按 less 排序的有序集合 ZSet。
+// less: 分数相同时元素的排序规则，为 nil 时使用默认的字典序；要求同 NewZSetFunc。
+// 返回新创建的 ZSet 指针。
+func NewZSetWithComparator(less func(a, b string) bool) *ZSet[string] {
+	if less == nil {
+		return NewZSet()
+	}
+	return NewZSetFunc(less)
+}
+
+// NewZSetFromMap 以 map 中的元素和分数创建有序集合 ZSet，元素为字符串。
+// m: 元素到分数的映射，分数为 NaN 的元素会被忽略。
+// 返回新创建的 ZSet 指针，与逐个调用 Add 得到的集合相同。
+func NewZSetFromMap(m map[string]float64) *ZSet[string] {
+	z := NewZSet()
+	for ele, score := range m {
+		z.add(ele, score)
+	}
+	return z
+}
+
+// NewZSetWithSource 创建一个使用指定随机数源生成节点层级的有序集合 ZSet，元素为字符串。
+// src: 随机数源，相同种子和相同的插入序列会得到完全相同的跳跃表结构，便于复现问题和编写测试。
+// src 只在修改集合时使用，不能与其他 goroutine 共享；RandomMember 等随机查询仍使用包级共享的随机数生成器。
 // 返回新创建的 ZSet 指针。
-func NewZSet() *ZSet {
-	return &ZSet{
+func NewZSetWithSource(src rand.Source) *ZSet[string] {
+	z := NewZSet()
+	z.zsl.rand = rand.New(src)
+	return z
+}
+
+// ErrInvalidProbability 表示指定的层级概率不在 (0, 1) 内。
+var ErrInvalidProbability = errors.New("zset: level probability must be in (0, 1)")
+
+// NewZSetWithConfig 创建一个使用指定层级参数的有序集合 ZSet，元素为字符串。
+// p: 节点增加一层的概率，取值范围 (0, 1)；较小的 p 减少每个节点的平均层数和内存，较大的 p 缩短查找路径。
+// maxLevel: 最大层级，取值范围 [1, 64]；约 log(1/p)(n) 层即可支撑 n 个元素，过高只会增加头节点的大小。
+// 默认值为 SKIPLIST_P 和 SKIPLIST_MAXLEVEL；Clear、ReplaceAll、Clone 以及以该集合为模板的集合运算结果都沿用这两个参数。
+// 返回新创建的 ZSet 指针；p 不合法时返回 ErrInvalidProbability，maxLevel 不合法时返回 ErrInvalidMaxLevel。
+func NewZSetWithConfig(p float64, maxLevel int) (*ZSet[string], error) {
+	if !(p > 0 && p < 1) {
+		return nil, ErrInvalidProbability
+	}
+	if maxLevel < 1 || maxLevel > maxLevelLimit {
+		return nil, ErrInvalidMaxLevel
+	}
+
+	z := &ZSet[string]{
 		dict: make(map[string]float64),
-		zsl:  createSkiplist(),
+		zsl:  createSkiplist(maxLevel, func(a, b string) bool { return a < b }),
+	}
+	z.zsl.p = p
+	return z, nil
+}
+
+// defaultLess 返回元素类型 M 的默认排序规则，目前仅字符串元素有默认规则（字典序）。
+// 返回排序规则以及是否存在默认规则。
+func defaultLess[M comparable]() (func(a, b M) bool, bool) {
+	less, ok := any(func(a, b string) bool { return a < b }).(func(a, b M) bool)
+	return less, ok
+}
+
+// reset 清空集合和载荷并重建跳跃表，保留原有的最大层级和排序规则，调用方需持有写锁。
+// 对于零值 ZSet，使用默认的最大层级和元素类型的默认排序规则。
+// 如果零值 ZSet 的元素类型没有默认排序规则，返回 ErrNoComparator。
+func (z *ZSet[M]) reset() error {
+	maxLevel := SKIPLIST_MAXLEVEL
+	var less func(a, b M) bool
+	if z.zsl != nil {
+		maxLevel, less = z.zsl.maxLevel, z.zsl.less
+	} else {
+		var ok bool
+		if less, ok = defaultLess[M](); !ok {
+			return ErrNoComparator
+		}
+	}
+
+	var src *rand.Rand
+	p := SKIPLIST_P
+	if z.zsl != nil {
+		src, p = z.zsl.rand, z.zsl.p
+	}
+
+	z.dict = make(map[M]float64)
+	z.zsl = createSkiplist(maxLevel, less)
+	z.zsl.rand = src
+	z.zsl.p = p
+	if z.payloads != nil {
+		z.payloads = make(map[M]any)
 	}
+	return nil
 }
 
 // randomLevel 随机生成一个跳跃表节点的层级。
-// 返回生成的层级。
-func randomLevel() int {
+// 返回生成的层级，不超过跳跃表允许的最大层级。
+func (sl *skiplist[M]) randomLevel() int {
+	r := sl.rand
+	if r == nil {
+		// 共享的 rng 可能被多个集合同时使用
+		rngMu.Lock()
+		defer rngMu.Unlock()
+		r = rng
+	}
+
 	level := 1
-	for rng.Float64() < SKIPLIST_P && level < SKIPLIST_MAXLEVEL {
+	for r.Float64() < sl.p && level < sl.maxLevel {
 		level++
 	}
 	return level
@@ -96,9 +324,10 @@ func randomLevel() int {
 // score: 节点的分数。
 // ele: 节点的元素值。
 // 返回新插入的节点指针。
-func (sl *skiplist) insert(score float64, ele string) *skiplistNode {
-	update := make([]*skiplistNode, SKIPLIST_MAXLEVEL)
-	rank := make([]uint64, SKIPLIST_MAXLEVEL)
+func (sl *skiplist[M]) insert(score float64, ele M) *skiplistNode[M] {
+	var updateBuf [maxLevelLimit]*skiplistNode[M]
+	var rankBuf [maxLevelLimit]uint64
+	update, rank := updateBuf[:sl.maxLevel], rankBuf[:sl.maxLevel]
 
 	// 查找插入位置
 	x := sl.header
@@ -111,7 +340,7 @@ func (sl *skiplist) insert(score float64, ele string) *skiplistNode {
 
 		for x.level[i].forward != nil &&
 			(x.level[i].forward.score < score ||
-				(x.level[i].forward.score == score && x.level[i].forward.ele < ele)) {
+				(x.level[i].forward.score == score && sl.less(x.level[i].forward.ele, ele))) {
 			rank[i] += x.level[i].span
 			x = x.level[i].forward
 		}
@@ -119,7 +348,7 @@ func (sl *skiplist) insert(score float64, ele string) *skiplistNode {
 	}
 
 	// 随机生成新节点的层级
-	level := randomLevel()
+	level := sl.randomLevel()
 
 	// 如果新节点的层级大于当前跳跃表的层级
 	if level > sl.level {
@@ -132,7 +361,7 @@ func (sl *skiplist) insert(score float64, ele string) *skiplistNode {
 	}
 
 	// 创建新节点
-	x = createNode(level, score, ele)
+	x = sl.newNode(level, score, ele)
 
 	// 插入节点到跳跃表
 	for i := 0; i < level; i++ {
@@ -168,45 +397,228 @@ func (sl *skiplist) insert(score float64, ele string) *skiplistNode {
 
 // Add 向 ZSet 中添加或更新元素。
 // ele: 要添加的元素。
-// score: 元素的分数。
+// score: 元素的分数，NaN 会被拒绝，此时不做任何修改并返回 false。
 // 如果元素是新添加的，返回 true；如果元素已存在且分数被更新，返回 false。
-func (z *ZSet) Add(ele string, score float64) bool {
+func (z *ZSet[M]) Add(ele M, score float64) bool {
+	z.lock()
+	defer z.unlock()
+
+	return z.add(ele, score)
+}
+
+// AddWithPrev 向 ZSet 中添加或更新元素，并返回元素原来的分数。
+// ele: 要添加的元素。
+// score: 元素的分数。
+// 返回原分数和元素此前是否存在，元素不存在时原分数为 0。
+// score 为 NaN 时不做任何修改。
+func (z *ZSet[M]) AddWithPrev(ele M, score float64) (prevScore float64, existed bool) {
+	z.lock()
+	defer z.unlock()
+
+	prevScore, existed = z.dict[ele]
+	z.add(ele, score)
+	return prevScore, existed
+}
+
+// add 向 ZSet 中添加或更新元素，调用方需持有写锁。
+// ele: 要添加的元素。
+// score: 元素的分数。
+// 如果元素是新添加的，返回 true；否则返回 false。
+func (z *ZSet[M]) add(ele M, score float64) bool {
+	_, added := z.upsert(ele, score)
+	return added
+}
+
+// upsert 向 ZSet 中添加或更新元素，调用方需持有写锁。
+// ele: 要添加的元素。
+// score: 元素的分数，为 NaN 时不做任何修改。
+// 返回元素所在的节点（score 为 NaN 时为 nil）以及元素是否为新添加。
+func (z *ZSet[M]) upsert(ele M, score float64) (*skiplistNode[M], bool) {
+	// NaN 与任何分数比较都为 false，插入后会破坏跳跃表的有序性
+	if math.IsNaN(score) {
+		return nil, false
+	}
+
 	// 检查元素是否已存在
 	oldScore, exists := z.dict[ele]
 
-	// 如果元素已存在且分数相同，不做任何操作
-	if exists && oldScore == score {
+	var x *skiplistNode[M]
+	if exists {
+		x = z.zsl.updateScore(oldScore, ele, score)
+	} else {
+		x = z.zsl.insert(score, ele)
+	}
+
+	// 更新哈希表
+	z.dict[ele] = score
+
+	return x, !exists
+}
+
+// updateScore 更新跳跃表中已存在节点的分数，语义同 Redis 的 zslUpdateScore。
+// curScore: 节点当前的分数。
+// ele: 节点的元素值。
+// newScore: 新的分数。
+// 新分数不改变节点位置时原地修改，否则将节点移动到新位置。
+// 返回更新后元素所在的节点。
+func (sl *skiplist[M]) updateScore(curScore float64, ele M, newScore float64) *skiplistNode[M] {
+	var updateBuf [maxLevelLimit]*skiplistNode[M]
+	update := updateBuf[:sl.maxLevel]
+
+	// 查找要更新的节点
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil &&
+			(x.level[i].forward.score < curScore ||
+				(x.level[i].forward.score == curScore && sl.less(x.level[i].forward.ele, ele))) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	x = x.level[0].forward
+	if x == nil || x.score != curScore || x.ele != ele {
+		// 调用方保证节点存在，这里只做防御
+		return sl.insert(newScore, ele)
+	}
+	if newScore == curScore {
+		return x
+	}
+
+	// 新分数仍严格位于前后节点之间时位置不变，直接修改分数
+	if (x.backward == nil || x.backward.score < newScore) &&
+		(x.level[0].forward == nil || x.level[0].forward.score > newScore) {
+		x.score = newScore
+		return x
+	}
+
+	// 否则删除后重新插入
+	sl.deleteNode(x, update)
+	n := sl.insert(newScore, ele)
+	sl.freeNode(x)
+	return n
+}
+
+var (
+	// ErrMemberNotFound 表示元素不存在于集合中。
+	ErrMemberNotFound = errors.New("zset: member not found")
+	// ErrEmptySet 表示集合为空。
+	ErrEmptySet = errors.New("zset: empty set")
+	// ErrInvalidScore 表示分数不合法（NaN，或 IncrByChecked 的结果溢出为无穷大）。
+	ErrInvalidScore = errors.New("zset: invalid score")
+	// ErrRankOutOfRange 表示排名超出集合范围。
+	ErrRankOutOfRange = errors.New("zset: rank out of range")
+)
+
+// UpdateScore 更新已存在元素的分数，元素不存在时不会插入。
+// ele: 要更新的元素。
+// score: 新的分数。
+// 元素不存在时返回 ErrMemberNotFound，score 为 NaN 时返回 ErrInvalidScore。
+func (z *ZSet[M]) UpdateScore(ele M, score float64) error {
+	z.lock()
+	defer z.unlock()
+
+	if math.IsNaN(score) {
+		return ErrInvalidScore
+	}
+	if _, exists := z.dict[ele]; !exists {
+		return ErrMemberNotFound
+	}
+	z.add(ele, score)
+	return nil
+}
+
+// IncrByChecked 将 ZSet 中指定元素的分数增加 delta，结果不是有限数时拒绝修改。
+// ele: 要增加分数的元素，不存在时视为分数为 0 并插入。
+// delta: 分数增量，可以为负数。
+// 返回增加后的分数；结果为 ±Inf（如超出 math.MaxFloat64）或 NaN 时返回 ErrInvalidScore，且集合保持不变。
+func (z *ZSet[M]) IncrByChecked(ele M, delta float64) (float64, error) {
+	z.lock()
+	defer z.unlock()
+
+	old := z.dict[ele]
+	score := old + delta
+	if math.IsNaN(score) || math.IsInf(score, 0) {
+		return old, ErrInvalidScore
+	}
+	z.add(ele, score)
+	return score, nil
+}
+
+// AddOptions 是 AddOpt 的条件选项，对应 Redis ZADD 的 NX/XX/GT/LT 参数。
+// NX 与 XX、GT、LT 互斥，GT 与 LT 互斥；冲突的组合不会修改集合。
+type AddOptions struct {
+	NX bool // 只添加新元素，不更新已存在的元素
+	XX bool // 只更新已存在的元素，不添加新元素
+	GT bool // 只在新分数大于原分数时更新，不影响新元素的添加
+	LT bool // 只在新分数小于原分数时更新，不影响新元素的添加
+}
+
+// valid 检查选项组合是否合法。
+func (o AddOptions) valid() bool {
+	if o.NX && (o.XX || o.GT || o.LT) {
 		return false
 	}
+	return !(o.GT && o.LT)
+}
 
-	// 如果元素已存在，先从跳跃表中删除
-	if exists {
-		z.zsl.delete(oldScore, ele)
+// AddOpt 按条件向 ZSet 中添加或更新元素，语义同 Redis ZADD NX|XX GT|LT。
+// ele: 要添加的元素。
+// score: 元素的分数。
+// opts: 条件选项，选项组合冲突或 score 为 NaN 时不做任何修改。
+// 返回元素是否被新添加、已存在的元素是否满足条件并写入了新分数，
+// 以及集合中保存的分数是否因此改变（新添加的元素也算改变，同 Redis ZADD CH）；写入相同分数时 updated 为 true 而 changed 为 false。
+func (z *ZSet[M]) AddOpt(ele M, score float64, opts AddOptions) (added bool, updated bool, changed bool) {
+	z.lock()
+	defer z.unlock()
+
+	if !opts.valid() || math.IsNaN(score) {
+		return false, false, false
 	}
 
-	// 插入新元素到跳跃表
-	//node := z.zsl.insert(score, ele)
-	z.zsl.insert(score, ele)
+	oldScore, exists := z.dict[ele]
+	if !exists {
+		if opts.XX {
+			return false, false, false
+		}
+		z.add(ele, score)
+		return true, false, true
+	}
 
-	// 更新哈希表
-	z.dict[ele] = score
+	if opts.NX || (opts.GT && score <= oldScore) || (opts.LT && score >= oldScore) {
+		return false, false, false
+	}
+	z.add(ele, score)
+	return false, true, score != oldScore
+}
 
-	return !exists
+// IncrBy 将 ZSet 中指定元素的分数增加 delta，语义同 Redis ZINCRBY。
+// ele: 要增加分数的元素，不存在时视为分数为 0 并插入。
+// delta: 分数增量，可以为负数。
+// 返回增加后的分数；结果为 NaN 时（如 delta 为 NaN 或 +Inf 加 -Inf）不做任何修改并返回 NaN。
+func (z *ZSet[M]) IncrBy(ele M, delta float64) float64 {
+	z.lock()
+	defer z.unlock()
+
+	score := z.dict[ele] + delta
+	z.add(ele, score)
+	return score
 }
 
 // delete 从跳跃表中删除指定分数和元素的节点。
 // score: 节点的分数。
 // ele: 节点的元素值。
 // 如果成功删除，返回 true；否则返回 false。
-func (sl *skiplist) delete(score float64, ele string) bool {
-	update := make([]*skiplistNode, SKIPLIST_MAXLEVEL)
+func (sl *skiplist[M]) delete(score float64, ele M) bool {
+	var updateBuf [maxLevelLimit]*skiplistNode[M]
+	update := updateBuf[:sl.maxLevel]
 
 	// 查找要删除的节点
 	x := sl.header
 	for i := sl.level - 1; i >= 0; i-- {
 		for x.level[i].forward != nil &&
 			(x.level[i].forward.score < score ||
-				(x.level[i].forward.score == score && x.level[i].forward.ele < ele)) {
+				(x.level[i].forward.score == score && sl.less(x.level[i].forward.ele, ele))) {
 			x = x.level[i].forward
 		}
 		update[i] = x
@@ -218,6 +630,7 @@ func (sl *skiplist) delete(score float64, ele string) bool {
 	// 检查是否找到了要删除的节点
 	if x != nil && x.score == score && x.ele == ele {
 		sl.deleteNode(x, update)
+		sl.freeNode(x)
 		return true
 	}
 
@@ -227,14 +640,17 @@ func (sl *skiplist) delete(score float64, ele string) bool {
 // deleteNode 删除跳跃表中的指定节点。
 // x: 要删除的节点。
 // update: 记录需要更新的节点。
-func (sl *skiplist) deleteNode(x *skiplistNode, update []*skiplistNode) {
+func (sl *skiplist[M]) deleteNode(x *skiplistNode[M], update []*skiplistNode[M]) {
 	// 更新前向指针和跨度
 	for i := 0; i < sl.level; i++ {
 		if update[i].level[i].forward == x {
 			update[i].level[i].span += x.level[i].span - 1
 			update[i].level[i].forward = x.level[i].forward
-		} else {
+		} else if update[i].level[i].span > 0 {
+			// update[i] 在第 i 层跨过 x（或一直到末尾），跨度中包含 x，结构正确时至少为 1
 			update[i].level[i].span--
+		} else if debugChecks {
+			panic("zset: zero span above deleted node at level " + strconv.Itoa(i))
 		}
 	}
 
@@ -256,7 +672,17 @@ func (sl *skiplist) deleteNode(x *skiplistNode, update []*skiplistNode) {
 // Remove 从 ZSet 中删除指定元素。
 // ele: 要删除的元素。
 // 如果元素存在并成功删除，返回 true；否则返回 false。
-func (z *ZSet) Remove(ele string) bool {
+func (z *ZSet[M]) Remove(ele M) bool {
+	z.lock()
+	defer z.unlock()
+
+	return z.remove(ele)
+}
+
+// remove 从 ZSet 中删除指定元素，调用方需持有写锁。
+// ele: 要删除的元素。
+// 如果元素存在并成功删除，返回 true；否则返回 false。
+func (z *ZSet[M]) remove(ele M) bool {
 	// 检查元素是否存在
 	score, exists := z.dict[ele]
 	if !exists {
@@ -268,6 +694,7 @@ func (z *ZSet) Remove(ele string) bool {
 
 	// 从哈希表中删除
 	delete(z.dict, ele)
+	delete(z.payloads, ele)
 
 	return true
 }
@@ -275,23 +702,97 @@ func (z *ZSet) Remove(ele string) bool {
 // Score 获取 ZSet 中指定元素的分数。
 // ele: 要获取分数的元素。
 // 返回元素的分数和元素是否存在的标志。
-func (z *ZSet) Score(ele string) (float64, bool) {
+func (z *ZSet[M]) Score(ele M) (float64, bool) {
+	z.rlock()
+	defer z.runlock()
+
 	score, exists := z.dict[ele]
 	return score, exists
 }
 
+// Contains 判断元素是否存在于 ZSet 中，O(1)。
+// ele: 要判断的元素。
+// 元素存在时返回 true。
+func (z *ZSet[M]) Contains(ele M) bool {
+	z.rlock()
+	defer z.runlock()
+
+	_, exists := z.dict[ele]
+	return exists
+}
+
+// ScoreE 获取 ZSet 中指定元素的分数，元素不存在时返回错误。
+// ele: 要获取分数的元素。
+// 返回元素的分数，元素不存在时返回 ErrMemberNotFound。
+func (z *ZSet[M]) ScoreE(ele M) (float64, error) {
+	score, exists := z.Score(ele)
+	if !exists {
+		return 0, ErrMemberNotFound
+	}
+	return score, nil
+}
+
+// MScore 批量获取多个元素的分数，同 Redis ZMSCORE。
+// members: 要查询的元素。
+// 返回与 members 顺序一致的结果，元素不存在时 Exists 为 false、Score 为 0。
+func (z *ZSet[M]) MScore(members ...M) []struct {
+	Score  float64
+	Exists bool
+} {
+	z.rlock()
+	defer z.runlock()
+
+	result := make([]struct {
+		Score  float64
+		Exists bool
+	}, len(members))
+	for i, ele := range members {
+		result[i].Score, result[i].Exists = z.dict[ele]
+	}
+	return result
+}
+
+// ScoresOf 批量获取多个元素的分数，只返回存在的元素。
+// members: 要查询的元素，可以重复。
+// 返回存在的元素到分数的映射，不存在的元素被跳过；没有元素存在时返回空映射而非 nil。
+func (z *ZSet[M]) ScoresOf(members ...M) map[M]float64 {
+	z.rlock()
+	defer z.runlock()
+
+	result := make(map[M]float64, len(members))
+	for _, ele := range members {
+		if score, exists := z.dict[ele]; exists {
+			result[ele] = score
+		}
+	}
+	return result
+}
+
 // Rank 获取 ZSet 中指定元素的排名。
+// 先在哈希表中 O(1) 取得分数，元素不存在时直接返回；存在时沿跳跃表下降一次累加跨度，O(log n)。
+// ele: 要获取排名的元素。
+// reverse: 是否按降序排名。
+// 返回元素的排名（从 0 开始），如果元素不存在返回 -1。
+func (z *ZSet[M]) Rank(ele M, reverse bool) int64 {
+	z.rlock()
+	defer z.runlock()
+
+	return z.rank(ele, reverse)
+}
+
+// rank 获取元素的排名，调用方需持有锁。
 // ele: 要获取排名的元素。
 // reverse: 是否按降序排名。
 // 返回元素的排名（从 0 开始），如果元素不存在返回 -1。
-func (z *ZSet) Rank(ele string, reverse bool) int64 {
+func (z *ZSet[M]) rank(ele M, reverse bool) int64 {
 	score, exists := z.dict[ele]
 	if !exists {
 		return -1
 	}
 
 	rank := z.zsl.getRank(score, ele)
-	if rank == 0 {
+	if rank == 0 || rank > z.zsl.length {
+		// 排名超过长度说明跨度或长度已损坏，返回 -1 而不是换算出错误的排名，可用 Validate 定位
 		return -1
 	}
 
@@ -304,13 +805,60 @@ func (z *ZSet) Rank(ele string, reverse bool) int64 {
 	return int64(rank)
 }
 
+// RankE 获取 ZSet 中指定元素的排名，元素不存在时返回错误。
+// ele: 要获取排名的元素。
+// reverse: 是否按降序排名。
+// 返回元素的排名（从 0 开始），元素不存在时返回 ErrMemberNotFound。
+func (z *ZSet[M]) RankE(ele M, reverse bool) (int64, error) {
+	rank := z.Rank(ele, reverse)
+	if rank < 0 {
+		return -1, ErrMemberNotFound
+	}
+	return rank, nil
+}
+
+// MRank 批量获取多个元素的排名。
+// reverse: 是否按降序排名。
+// members: 要查询的元素。
+// 返回与 members 顺序一致的排名（从 0 开始），元素不存在时为 -1。
+func (z *ZSet[M]) MRank(reverse bool, members ...M) []int64 {
+	z.rlock()
+	defer z.runlock()
+
+	ranks := make([]int64, len(members))
+	for i, ele := range members {
+		ranks[i] = z.rank(ele, reverse)
+	}
+	return ranks
+}
+
+// RankWithScore 同时获取 ZSet 中指定元素的排名和分数。
+// ele: 要查询的元素。
+// reverse: 是否按降序排名。
+// 返回元素的排名（从 0 开始）、分数和是否存在的标志，元素不存在时排名为 -1。
+func (z *ZSet[M]) RankWithScore(ele M, reverse bool) (rank int64, score float64, ok bool) {
+	z.rlock()
+	defer z.runlock()
+
+	rank = z.rank(ele, reverse)
+	if rank < 0 {
+		return -1, 0, false
+	}
+	return rank, z.dict[ele], true
+}
+
 // GetByRank 获取 ZSet 中指定排名的元素。
-// rank: 要获取的排名（从 0 开始）。
+// rank: 要获取的排名（从 0 开始），负数表示从末尾倒数，-1 为最后一个元素（reverse 为 true 时即分数最低的元素）。
 // reverse: 是否按降序排名。
-// 返回元素、元素的分数和元素是否存在的标志。
-func (z *ZSet) GetByRank(rank int64, reverse bool) (string, float64, bool) {
-	if rank < 0 || rank >= int64(z.zsl.length) {
-		return "", 0, false
+// 返回元素、元素的分数和元素是否存在的标志，排名超出 [-Len(), Len()) 时返回 false。
+func (z *ZSet[M]) GetByRank(rank int64, reverse bool) (M, float64, bool) {
+	z.rlock()
+	defer z.runlock()
+
+	var zero M
+	rank, ok := normalizeRank(rank, z.zsl.length)
+	if !ok {
+		return zero, 0, false
 	}
 
 	if reverse {
@@ -319,16 +867,44 @@ func (z *ZSet) GetByRank(rank int64, reverse bool) (string, float64, bool) {
 
 	n := z.zsl.getElementByRank(uint64(rank + 1))
 	if n == nil {
-		return "", 0, false
+		return zero, 0, false
 	}
 
 	return n.ele, n.score, true
 }
 
+// GetByRankE 获取 ZSet 中指定排名的元素，排名无效时返回错误。
+// rank: 要获取的排名（从 0 开始），负数表示从末尾倒数，语义同 GetByRank。
+// reverse: 是否按降序排名。
+// 返回元素和分数；集合为空时返回 ErrEmptySet，排名超出 [-Len(), Len()) 时返回 ErrRankOutOfRange。
+func (z *ZSet[M]) GetByRankE(rank int64, reverse bool) (M, float64, error) {
+	z.rlock()
+	defer z.runlock()
+
+	var zero M
+	if z.zsl.length == 0 {
+		return zero, 0, ErrEmptySet
+	}
+	rank, ok := normalizeRank(rank, z.zsl.length)
+	if !ok {
+		return zero, 0, ErrRankOutOfRange
+	}
+
+	if reverse {
+		rank = int64(z.zsl.length) - 1 - rank
+	}
+
+	n := z.zsl.getElementByRank(uint64(rank + 1))
+	if n == nil {
+		return zero, 0, ErrRankOutOfRange
+	}
+	return n.ele, n.score, nil
+}
+
 // getElementByRank 获取跳跃表中指定排名的节点。
 // rank: 要获取的排名（从 1 开始）。
 // 返回指定排名的节点指针，如果排名无效返回 nil。
-func (sl *skiplist) getElementByRank(rank uint64) *skiplistNode {
+func (sl *skiplist[M]) getElementByRank(rank uint64) *skiplistNode[M] {
 	if rank == 0 || rank > sl.length {
 		return nil
 	}
@@ -346,21 +922,31 @@ func (sl *skiplist) getElementByRank(rank uint64) *skiplistNode {
 		}
 	}
 
+	// 逐层下降后仍未命中说明上层跨度与实际节点数不一致，
+	// 退化为沿第 0 层逐个前进，保证 1 <= rank <= length 时总能返回节点
+	for x.level[0].forward != nil && traversed < rank {
+		traversed++
+		x = x.level[0].forward
+	}
+	if traversed == rank && x != sl.header {
+		return x
+	}
 	return nil
 }
 
 // getRank 获取元素在跳跃表中的排名。
+// 与查找使用同一次自顶向下的下降，沿途累加跨度，在某一层到达目标节点后立即返回，不会再遍历第 0 层。
 // score: 元素的分数。
 // ele: 元素的值。
 // 返回元素的排名（从 1 开始），如果元素不存在返回 0。
-func (sl *skiplist) getRank(score float64, ele string) uint64 {
+func (sl *skiplist[M]) getRank(score float64, ele M) uint64 {
 	var rank uint64 = 0
 	x := sl.header
 
 	for i := sl.level - 1; i >= 0; i-- {
 		for x.level[i].forward != nil &&
 			(x.level[i].forward.score < score ||
-				(x.level[i].forward.score == score && x.level[i].forward.ele <= ele)) {
+				(x.level[i].forward.score == score && !sl.less(ele, x.level[i].forward.ele))) {
 			rank += x.level[i].span
 			x = x.level[i].forward
 		}
@@ -374,18 +960,91 @@ func (sl *skiplist) getRank(score float64, ele string) uint64 {
 }
 
 // RangeByScore 按分数范围获取 ZSet 中的元素。
-// min: 分数范围的最小值。
-// max: 分数范围的最大值。
+// min: 分数范围的最小值，math.Inf(-1) 表示不设下界。
+// max: 分数范围的最大值，math.Inf(1) 表示不设上界。
 // offset: 跳过的元素数量。
 // count: 要获取的元素数量，-1 表示获取所有符合条件的元素。
-// 返回符合条件的元素列表。
-func (z *ZSet) RangeByScore(min, max float64, offset, count int64) []struct {
-	Member string
+// 返回符合条件的元素列表，min 大于 max 时返回 nil。
+func (z *ZSet[M]) RangeByScore(min, max float64, offset, count int64) []struct {
+	Member M
 	Score  float64
 } {
-	var result []struct {
-		Member string
-		Score  float64
+	return z.RangeByScoreBounds(min, false, max, false, offset, count)
+}
+
+// scoreGteMin 判断分数是否满足下界。
+// score: 要判断的分数。
+// min: 分数下界。
+// exclusive: 下界是否为开区间。
+func scoreGteMin(score, min float64, exclusive bool) bool {
+	if exclusive {
+		return score > min
+	}
+	return score >= min
+}
+
+// scoreLteMax 判断分数是否满足上界。
+// score: 要判断的分数。
+// max: 分数上界。
+// exclusive: 上界是否为开区间。
+func scoreLteMax(score, max float64, exclusive bool) bool {
+	if exclusive {
+		return score < max
+	}
+	return score <= max
+}
+
+// RangeByScoreBounds 按分数范围获取 ZSet 中的元素，两端可分别指定为开区间。
+// min: 分数范围的最小值，math.Inf(-1) 表示不设下界，从跳跃表头部开始。
+// minExclusive: 是否排除分数等于 min 的元素，相当于 Redis 的 "(min"。
+// max: 分数范围的最大值，math.Inf(1) 表示不设上界，一直扫描到跳跃表尾部。
+// maxExclusive: 是否排除分数等于 max 的元素，相当于 Redis 的 "(max"。
+// offset: 跳过的元素数量。
+// count: 要获取的元素数量，-1 表示获取所有符合条件的元素。
+// 返回符合条件的元素列表，min 大于 max 时返回 nil。
+func (z *ZSet[M]) RangeByScoreBounds(min float64, minExclusive bool, max float64, maxExclusive bool, offset, count int64) []struct {
+	Member M
+	Score  float64
+} {
+	z.rlock()
+	defer z.runlock()
+
+	return z.appendRangeByScore(nil, min, minExclusive, max, maxExclusive, offset, count)
+}
+
+// AppendRangeByScore 按分数范围获取元素并追加到 dst 中，语义与 RangeByScore 相同。
+// 调用方可以传入 dst[:0] 在多次调用间复用缓冲区，容量足够时不产生分配。
+// dst: 结果追加到的切片。
+// min: 分数范围的最小值（包含）。
+// max: 分数范围的最大值（包含）。
+// offset: 跳过的元素数量。
+// count: 要获取的元素数量，-1 表示获取所有符合条件的元素。
+// 返回追加结果后的切片，min 大于 max 时原样返回 dst。
+func (z *ZSet[M]) AppendRangeByScore(dst []struct {
+	Member M
+	Score  float64
+}, min, max float64, offset, count int64) []struct {
+	Member M
+	Score  float64
+} {
+	z.rlock()
+	defer z.runlock()
+
+	return z.appendRangeByScore(dst, min, false, max, false, offset, count)
+}
+
+// appendRangeByScore 将分数位于范围内的元素追加到 result 中，调用方需持有读锁。
+// 参数语义同 RangeByScoreBounds，返回追加后的切片。
+func (z *ZSet[M]) appendRangeByScore(result []struct {
+	Member M
+	Score  float64
+}, min float64, minExclusive bool, max float64, maxExclusive bool, offset, count int64) []struct {
+	Member M
+	Score  float64
+} {
+	// 上下界颠倒或为 NaN 时范围为空，无需遍历跳跃表
+	if !(min <= max) {
+		return result
 	}
 
 	// 找到范围的起始节点
@@ -396,7 +1055,7 @@ func (z *ZSet) RangeByScore(min, max float64, offset, count int64) []struct {
 
 	// 跳到最小分数位置
 	for i := z.zsl.level - 1; i >= 0; i-- {
-		for x.level[i].forward != nil && x.level[i].forward.score < min {
+		for x.level[i].forward != nil && !scoreGteMin(x.level[i].forward.score, min, minExclusive) {
 			x = x.level[i].forward
 		}
 	}
@@ -407,7 +1066,7 @@ func (z *ZSet) RangeByScore(min, max float64, offset, count int64) []struct {
 	// 跳过 offset 个元素
 	var skipped int64 = 0
 	for x != nil && skipped < offset {
-		if x.score > max {
+		if !scoreLteMax(x.score, max, maxExclusive) {
 			break
 		}
 		skipped++
@@ -417,12 +1076,12 @@ func (z *ZSet) RangeByScore(min, max float64, offset, count int64) []struct {
 	// 收集结果
 	var returned int64 = 0
 	for x != nil && (count < 0 || returned < count) {
-		if x.score > max {
+		if !scoreLteMax(x.score, max, maxExclusive) {
 			break
 		}
 
 		result = append(result, struct {
-			Member string
+			Member M
 			Score  float64
 		}{
 			Member: x.ele,
@@ -436,8 +1095,1804 @@ func (z *ZSet) RangeByScore(min, max float64, offset, count int64) []struct {
 	return result
 }
 
-// Len 获取 ZSet 中元素的数量。
-// 返回 ZSet 中元素的数量。
-func (z *ZSet) Len() uint64 {
-	return z.zsl.length
+// RangeByScoreMulti 在一次读锁内按多个分数区间获取元素，各区间的结果来自集合的同一状态。
+// 每个区间从跳跃表顶层下降一次定位起点，再沿第 0 层收集，总复杂度 O(k log n + m)，k 为区间数量，m 为结果总数。
+// windows: 分数区间列表，windows[i] 为 [min, max]（两端包含），区间之间可以相邻或重叠。
+// 返回与 windows 一一对应的结果，没有匹配元素的区间对应 nil；任一区间 min 大于 max 或含 NaN 时返回 nil。
+func (z *ZSet[M]) RangeByScoreMulti(windows [][2]float64) [][]struct {
+	Member M
+	Score  float64
+} {
+	z.rlock()
+	defer z.runlock()
+
+	// 校验区间
+	for _, w := range windows {
+		if !(w[0] <= w[1]) {
+			return nil
+		}
+	}
+
+	result := make([][]struct {
+		Member M
+		Score  float64
+	}, len(windows))
+	for i, w := range windows {
+		result[i] = z.appendRangeByScore(nil, w[0], false, w[1], false, 0, -1)
+	}
+	return result
+}
+
+// RangeByScorePaged 按分数范围分页获取元素，同时返回范围内的元素总数。
+// min: 分数范围的最小值（包含）。
+// max: 分数范围的最大值（包含）。
+// offset: 跳过的元素数量。
+// count: 要获取的元素数量，-1 表示获取所有符合条件的元素。
+// 返回当前页的元素列表，以及分数位于 [min, max] 的元素总数（与 offset、count 无关，等于 Count(min, max)）。
+// 两端位置都通过跳跃表跨度计算，只遍历当前页的元素；min > max 或任一端为 NaN 时返回 nil 和 0。
+func (z *ZSet[M]) RangeByScorePaged(min, max float64, offset, count int64) (items []struct {
+	Member M
+	Score  float64
+}, total int64) {
+	z.rlock()
+	defer z.runlock()
+
+	if !(min <= max) {
+		return items, 0
+	}
+
+	below := int64(z.zsl.countBelow(min, false))
+	total = int64(z.zsl.countBelow(max, true)) - below
+	if offset < 0 {
+		offset = 0
+	}
+	if total <= offset || count == 0 {
+		return items, total
+	}
+
+	// 与 total-offset 比较而不是计算 offset+count，count 很大时不会溢出
+	stop := below + total - 1
+	if count > 0 && count < total-offset {
+		stop = below + offset + count - 1
+	}
+	z.walkRank(below+offset, stop, false, func(x *skiplistNode[M], _ int64) bool {
+		items = append(items, struct {
+			Member M
+			Score  float64
+		}{
+			Member: x.ele,
+			Score:  x.score,
+		})
+		return true
+	})
+	return items, total
+}
+
+// RevRangeByScore 按分数范围降序获取 ZSet 中的元素，语义同 Redis ZREVRANGEBYSCORE。
+// max: 分数范围的最大值，math.Inf(1) 表示不设上界。
+// min: 分数范围的最小值，math.Inf(-1) 表示不设下界。
+// offset: 按降序跳过的元素数量。
+// count: 要获取的元素数量，-1 表示获取所有符合条件的元素。
+// 返回按分数降序排列的元素列表，min 大于 max 时返回 nil。
+func (z *ZSet[M]) RevRangeByScore(max, min float64, offset, count int64) []struct {
+	Member M
+	Score  float64
+} {
+	z.rlock()
+	defer z.runlock()
+
+	var result []struct {
+		Member M
+		Score  float64
+	}
+
+	if !(min <= max) {
+		return result
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	// 找到分数小于等于 max 的最后一个节点，再沿后向指针移动
+	x := z.zsl.lastBelow(max, true)
+	if x == z.zsl.header {
+		return result
+	}
+
+	// 跳过 offset 个元素
+	var skipped int64 = 0
+	for x != nil && skipped < offset {
+		if x.score < min {
+			break
+		}
+		skipped++
+		x = x.backward
+	}
+
+	// 收集结果
+	var returned int64 = 0
+	for x != nil && (count < 0 || returned < count) {
+		if x.score < min {
+			break
+		}
+
+		result = append(result, struct {
+			Member M
+			Score  float64
+		}{
+			Member: x.ele,
+			Score:  x.score,
+		})
+
+		returned++
+		x = x.backward
+	}
+
+	return result
+}
+
+// Len 获取 ZSet 中元素的数量。
+// 返回 ZSet 中元素的数量。
+func (z *ZSet[M]) Len() uint64 {
+	z.rlock()
+	defer z.runlock()
+
+	return z.zsl.length
+}
+
+// ChangedRanks 比较两个快照中成员的排名，返回排名发生变化的成员。
+// oldSet: 旧快照。
+// newSet: 新快照。
+// 排名按升序计算（从 0 开始）；新出现的成员 OldRank 为 -1，消失的成员 NewRank 为 -1。
+// 结果先按旧快照中的顺序列出仍存在或已消失的成员，再按新快照中的顺序列出新出现的成员。
+// 每个成员的排名都通过对应跳跃表查询，两个集合合计复杂度为 O(n log n)。
+func ChangedRanks[M comparable](oldSet, newSet *ZSet[M]) []struct {
+	Member  M
+	OldRank int64
+	NewRank int64
+} {
+	var result []struct {
+		Member  M
+		OldRank int64
+		NewRank int64
+	}
+
+	unlock := rlockAll(oldSet, newSet)
+	defer unlock()
+
+	// 遍历旧快照，比较每个成员在新快照中的排名
+	var oldRank int64 = 0
+	for x := oldSet.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		newRank := newSet.rank(x.ele, false)
+		if newRank != oldRank {
+			result = append(result, struct {
+				Member  M
+				OldRank int64
+				NewRank int64
+			}{
+				Member:  x.ele,
+				OldRank: oldRank,
+				NewRank: newRank,
+			})
+		}
+		oldRank++
+	}
+
+	// 遍历新快照，收集新出现的成员
+	var newRank int64 = 0
+	for x := newSet.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		if _, exists := oldSet.dict[x.ele]; !exists {
+			result = append(result, struct {
+				Member  M
+				OldRank int64
+				NewRank int64
+			}{
+				Member:  x.ele,
+				OldRank: -1,
+				NewRank: newRank,
+			})
+		}
+		newRank++
+	}
+
+	return result
+}
+
+// Tiers 按排名分界将 ZSet 划分为连续的若干档位。
+// boundaries: 排名分界（从 0 开始），必须升序且位于 [0, Len()] 内。
+// reverse: 是否按降序排名。
+// 第 i 档包含排名位于 [boundaries[i-1], boundaries[i]) 的元素，首档从 0 开始，末档到 Len() 结束，
+// 因此共返回 len(boundaries)+1 档；如果分界不合法返回 nil。
+func (z *ZSet[M]) Tiers(boundaries []int64, reverse bool) [][]struct {
+	Member M
+	Score  float64
+} {
+	z.rlock()
+	defer z.runlock()
+
+	// 校验分界
+	for i, b := range boundaries {
+		if b < 0 || b > int64(z.zsl.length) {
+			return nil
+		}
+		if i > 0 && b < boundaries[i-1] {
+			return nil
+		}
+	}
+
+	result := make([][]struct {
+		Member M
+		Score  float64
+	}, len(boundaries)+1)
+
+	// 选择遍历的起点
+	x := z.zsl.header.level[0].forward
+	if reverse {
+		x = z.zsl.tail
+	}
+
+	// 遍历一次，排名越过分界时切换到下一档
+	tier := 0
+	var rank int64 = 0
+	for x != nil {
+		for tier < len(boundaries) && rank >= boundaries[tier] {
+			tier++
+		}
+
+		result[tier] = append(result[tier], struct {
+			Member M
+			Score  float64
+		}{
+			Member: x.ele,
+			Score:  x.score,
+		})
+
+		rank++
+		if reverse {
+			x = x.backward
+		} else {
+			x = x.level[0].forward
+		}
+	}
+
+	return result
+}
+
+// RankMode 定义分数不对应任何元素时排名的取值方式。
+type RankMode int
+
+const (
+	// RankFloor 取分数小于等于目标分数的最大元素的排名。
+	RankFloor RankMode = iota
+	// RankCeil 取分数大于等于目标分数的最小元素的排名。
+	RankCeil
+)
+
+// countBelow 统计跳跃表中分数低于指定分数的节点数量。
+// score: 分数界限。
+// inclusive: 是否将分数等于界限的节点计入。
+// 返回满足条件的节点数量。
+func (sl *skiplist[M]) countBelow(score float64, inclusive bool) uint64 {
+	var count uint64 = 0
+	x := sl.header
+
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil &&
+			(x.level[i].forward.score < score ||
+				(inclusive && x.level[i].forward.score == score)) {
+			count += x.level[i].span
+			x = x.level[i].forward
+		}
+	}
+
+	return count
+}
+
+// lastBelow 查找跳跃表中分数低于指定分数的最后一个节点。
+// score: 分数界限。
+// inclusive: 是否将分数等于界限的节点视为低于界限。
+// 返回满足条件的最后一个节点，没有节点满足条件时返回头节点。
+func (sl *skiplist[M]) lastBelow(score float64, inclusive bool) *skiplistNode[M] {
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil &&
+			(x.level[i].forward.score < score ||
+				(inclusive && x.level[i].forward.score == score)) {
+			x = x.level[i].forward
+		}
+	}
+	return x
+}
+
+// Ceiling 查找分数大于等于 score 的最小元素。
+// score: 目标分数，为 NaN 时没有元素满足条件。
+// 返回元素、分数和是否存在的标志；多个元素分数相同时返回排序最靠前的元素。
+func (z *ZSet[M]) Ceiling(score float64) (M, float64, bool) {
+	z.rlock()
+	defer z.runlock()
+
+	// NaN 与任何分数比较都为假，lastBelow 会停在头节点，需单独排除
+	x := z.zsl.lastBelow(score, false).level[0].forward
+	if x == nil || math.IsNaN(score) {
+		var zero M
+		return zero, 0, false
+	}
+	return x.ele, x.score, true
+}
+
+// Floor 查找分数小于等于 score 的最大元素。
+// score: 目标分数。
+// 返回元素、分数和是否存在的标志；多个元素分数相同时返回排序最靠后的元素。
+func (z *ZSet[M]) Floor(score float64) (M, float64, bool) {
+	z.rlock()
+	defer z.runlock()
+
+	x := z.zsl.lastBelow(score, true)
+	if x == z.zsl.header {
+		var zero M
+		return zero, 0, false
+	}
+	return x.ele, x.score, true
+}
+
+// RankOfScore 获取指定分数在 ZSet 中对应的排名。
+// score: 目标分数，不要求有元素持有该分数，为 NaN 时没有元素满足条件。
+// mode: RankFloor 取分数小于等于 score 的最大元素，RankCeil 取分数大于等于 score 的最小元素。
+// reverse: 是否按降序排名。
+// 返回所选元素的排名（从 0 开始），如果没有元素满足条件返回 -1。
+func (z *ZSet[M]) RankOfScore(score float64, mode RankMode, reverse bool) int64 {
+	z.rlock()
+	defer z.runlock()
+
+	if math.IsNaN(score) {
+		return -1
+	}
+
+	var rank int64
+	switch mode {
+	case RankFloor:
+		// 分数小于等于 score 的元素中排在最后的一个
+		rank = int64(z.zsl.countBelow(score, true)) - 1
+		if rank < 0 {
+			return -1
+		}
+	case RankCeil:
+		// 分数大于等于 score 的元素中排在最前的一个
+		rank = int64(z.zsl.countBelow(score, false))
+		if rank >= int64(z.zsl.length) {
+			return -1
+		}
+	default:
+		return -1
+	}
+
+	if reverse {
+		return int64(z.zsl.length) - 1 - rank
+	}
+	return rank
+}
+
+// RankByScore 获取一个假想分数在升序排列中将占据的排名，即分数严格小于 score 的元素数量。
+// score: 目标分数，不要求有元素持有该分数。
+// 通过跳跃表跨度计算，复杂度为 O(log n)。
+func (z *ZSet[M]) RankByScore(score float64) int64 {
+	z.rlock()
+	defer z.runlock()
+
+	return int64(z.zsl.countBelow(score, false))
+}
+
+// CountStrictlyBetween 统计分数严格位于 (min, max) 区间内的元素数量，不包含两端。
+// min: 分数范围的下界（不包含）。
+// max: 分数范围的上界（不包含）。
+// 返回满足条件的元素数量，区间为空、上下界颠倒或为 NaN 时返回 0。
+func (z *ZSet[M]) CountStrictlyBetween(min, max float64) uint64 {
+	z.rlock()
+	defer z.runlock()
+
+	if !(min < max) {
+		return 0
+	}
+
+	// 分数小于 max 的数量减去分数小于等于 min 的数量
+	below := z.zsl.countBelow(max, false)
+	atOrBelowMin := z.zsl.countBelow(min, true)
+	if below <= atOrBelowMin {
+		return 0
+	}
+	return below - atOrBelowMin
+}
+
+// ErrInvalidPageToken 表示分页令牌无法解码。
+var ErrInvalidPageToken = errors.New("zset: invalid page token")
+
+// PageToken 生成指向指定元素之后位置的分页令牌。
+// ele: 上一页最后返回的元素。
+// score: 上一页最后返回元素的分数。
+// 返回由分数和元素编码得到的 URL 安全的 base64 字符串，元素无法编码时返回错误。
+func (z *ZSet[M]) PageToken(ele M, score float64) (string, error) {
+	data, err := encodeMember(ele)
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint64(buf, math.Float64bits(score))
+	copy(buf[8:], data)
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// RangeAfterToken 从分页令牌记录的位置之后继续获取元素。
+// token: 由 PageToken 生成的分页令牌，空字符串表示从头开始。
+// count: 要获取的元素数量，-1 表示获取之后的所有元素。
+// 返回严格排在令牌记录的 (score, ele) 之后的元素列表；令牌无法解码时返回 ErrInvalidPageToken。
+// 由于按 (score, ele) 定位而非按排名定位，两次调用之间插入或删除元素不会导致重复或遗漏。
+func (z *ZSet[M]) RangeAfterToken(token string, count int64) ([]struct {
+	Member M
+	Score  float64
+}, error) {
+	z.rlock()
+	defer z.runlock()
+
+	var result []struct {
+		Member M
+		Score  float64
+	}
+
+	x := z.zsl.header
+	if token != "" {
+		buf, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil || len(buf) < 8 {
+			return nil, ErrInvalidPageToken
+		}
+		score := math.Float64frombits(binary.BigEndian.Uint64(buf))
+		ele, err := decodeMember[M](buf[8:])
+		if err != nil {
+			return nil, ErrInvalidPageToken
+		}
+
+		// 跳到最后一个不大于 (score, ele) 的节点
+		for i := z.zsl.level - 1; i >= 0; i-- {
+			for x.level[i].forward != nil &&
+				(x.level[i].forward.score < score ||
+					(x.level[i].forward.score == score && !z.zsl.less(ele, x.level[i].forward.ele))) {
+				x = x.level[i].forward
+			}
+		}
+	}
+
+	// 收集结果
+	x = x.level[0].forward
+	var returned int64 = 0
+	for x != nil && (count < 0 || returned < count) {
+		result = append(result, struct {
+			Member M
+			Score  float64
+		}{
+			Member: x.ele,
+			Score:  x.score,
+		})
+
+		returned++
+		x = x.level[0].forward
+	}
+
+	return result, nil
+}
+
+// Scan 以游标方式增量遍历集合，游标为按升序排列的排名。
+// cursor: 起始游标，首次调用传入 0，之后传入上一次返回的 next。
+// count: 本次最多返回的元素数量，count <= 0 时按 10 处理。
+// 返回下一次调用的游标（遍历结束时为 0）和本次获取的元素。
+// 游标只记录排名，两次调用之间插入或删除排在游标之前的元素会使后续结果重复或遗漏元素；
+// 在集合不被修改时能不重复、不遗漏地遍历全部元素。需要在修改期间稳定翻页时使用 PageToken 和 RangeAfterToken。
+func (z *ZSet[M]) Scan(cursor uint64, count int64) (next uint64, items []struct {
+	Member M
+	Score  float64
+}) {
+	z.rlock()
+	defer z.runlock()
+
+	if count <= 0 {
+		count = 10
+	}
+	if cursor >= z.zsl.length {
+		return 0, items
+	}
+
+	// 先把 count 限制在剩余元素数量内，避免计算结束排名时溢出
+	stop := int64(z.zsl.length) - 1
+	if count < int64(z.zsl.length-cursor) {
+		stop = int64(cursor) + count - 1
+	}
+	z.walkRank(int64(cursor), stop, false, func(x *skiplistNode[M], _ int64) bool {
+		items = append(items, struct {
+			Member M
+			Score  float64
+		}{
+			Member: x.ele,
+			Score:  x.score,
+		})
+		return true
+	})
+
+	next = cursor + uint64(len(items))
+	if next >= z.zsl.length {
+		next = 0
+	}
+	return next, items
+}
+
+// TopNWeightedAverage 计算分数最高的 n 个元素按排名加权的平均分数。
+// n: 参与计算的元素数量，超过元素总数时取全部元素。
+// weight: 权重函数，参数为元素在前 n 名中的排名（从 0 开始，0 为最高分）。
+// 返回 sum(weight*score)/sum(weight) 以及是否计算成功；集合为空、n 不为正或权重之和为 0 时返回 false。
+func (z *ZSet[M]) TopNWeightedAverage(n int64, weight func(rankWithinTopN int64) float64) (float64, bool) {
+	z.rlock()
+	defer z.runlock()
+
+	if n <= 0 || z.zsl.length == 0 {
+		return 0, false
+	}
+
+	// 从尾节点开始按降序遍历
+	var weightedSum, weightSum float64
+	var rank int64 = 0
+	for x := z.zsl.tail; x != nil && rank < n; x = x.backward {
+		w := weight(rank)
+		weightedSum += w * x.score
+		weightSum += w
+		rank++
+	}
+
+	if weightSum == 0 {
+		return 0, false
+	}
+	return weightedSum / weightSum, true
+}
+
+// MergeCaseInsensitive 合并仅大小写不同的元素，仅对字符串元素生效，其他元素类型不做任何操作。
+// 规范化规则：所有元素统一转换为 strings.ToLower 得到的小写形式，
+// 大小写折叠后相同的元素合并为一个，保留其中的最高分数和该元素的载荷；
+// 最高分数相同时保留排序最靠前的元素的载荷。
+// 如果有元素被改写，会重建跳跃表和哈希表，未被合并的元素保留原有载荷。
+// 返回因合并而减少的元素数量。
+func (z *ZSet[M]) MergeCaseInsensitive() (merged int) {
+	z.lock()
+	defer z.unlock()
+
+	// 按排序顺序计算规范化后的元素、最高分数及其载荷
+	folded := make(map[M]float64, len(z.dict))
+	var payloads map[M]any
+	if z.payloads != nil {
+		payloads = make(map[M]any, len(z.payloads))
+	}
+	changed := false
+	for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		s, ok := any(x.ele).(string)
+		if !ok {
+			return 0
+		}
+		key := any(strings.ToLower(s)).(M)
+		if key != x.ele {
+			changed = true
+		}
+		if old, exists := folded[key]; !exists || x.score > old {
+			folded[key] = x.score
+			if payload, ok := z.payloads[x.ele]; ok {
+				payloads[key] = payload
+			} else {
+				delete(payloads, key)
+			}
+		}
+	}
+
+	if !changed {
+		return 0
+	}
+
+	merged = len(z.dict) - len(folded)
+
+	// 重建跳跃表和哈希表
+	z.dict, z.payloads = folded, payloads
+	src, p := z.zsl.rand, z.zsl.p
+	z.zsl = createSkiplist(z.zsl.maxLevel, z.zsl.less)
+	z.zsl.rand = src
+	z.zsl.p = p
+	for ele, score := range folded {
+		z.zsl.insert(score, ele)
+	}
+
+	return merged
+}
+
+// normalizeRange 将支持负数索引的排名区间转换为有效的排名区间。
+// start: 起始排名（从 0 开始），负数表示从末尾倒数。
+// stop: 结束排名（包含），负数表示从末尾倒数。
+// length: 集合的元素数量。
+// 返回截断到 [0, length-1] 的起止排名，以及区间是否非空。
+func normalizeRange(start, stop int64, length uint64) (int64, int64, bool) {
+	n := int64(length)
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop || start >= n {
+		return 0, 0, false
+	}
+	return start, stop, true
+}
+
+// normalizeRank 将支持负数索引的排名换算为 [0, length) 内的排名，-1 表示最后一个元素。
+// rank: 排名（从 0 开始），负数表示从末尾倒数。
+// length: 集合的长度。
+// 返回换算后的排名和排名是否有效的标志，超出 [-length, length) 时无效。
+func normalizeRank(rank int64, length uint64) (int64, bool) {
+	n := int64(length)
+	if rank < 0 {
+		rank += n
+	}
+	if rank < 0 || rank >= n {
+		return 0, false
+	}
+	return rank, true
+}
+
+// getNode 查找跳跃表中指定分数和元素的节点。
+// score: 节点的分数。
+// ele: 节点的元素值。
+// 返回找到的节点指针，如果不存在返回 nil。
+func (sl *skiplist[M]) getNode(score float64, ele M) *skiplistNode[M] {
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil &&
+			(x.level[i].forward.score < score ||
+				(x.level[i].forward.score == score && sl.less(x.level[i].forward.ele, ele))) {
+			x = x.level[i].forward
+		}
+	}
+
+	x = x.level[0].forward
+	if x != nil && x.score == score && x.ele == ele {
+		return x
+	}
+	return nil
+}
+
+// MemberFootprint 估算单个元素占用的内存字节数。
+// ele: 要估算的元素。
+// 返回估算的字节数和元素是否存在的标志。
+// 估算包括：跳跃表节点结构体本身、层级数组（节点层数 × 每层大小，层数随节点随机而不同）、
+// 字符串元素的内容，以及哈希表中键和值的大小；元素设置了载荷时再加上载荷映射中键和接口值的大小。
+// 字符串内容由节点和哈希表共享，只计算一次；载荷本身引用的内存、元素内部引用的其他内存、
+// 内存分配器的对齐填充和哈希表桶的额外开销均不计入，因此结果是下限估计。
+func (z *ZSet[M]) MemberFootprint(ele M) (uint64, bool) {
+	z.rlock()
+	defer z.runlock()
+
+	score, exists := z.dict[ele]
+	if !exists {
+		return 0, false
+	}
+
+	x := z.zsl.getNode(score, ele)
+	if x == nil {
+		return 0, false
+	}
+
+	size := uint64(unsafe.Sizeof(skiplistNode[M]{}))
+	size += uint64(len(x.level)) * uint64(unsafe.Sizeof(skiplistLevel[M]{}))
+	if s, ok := any(x.ele).(string); ok {
+		size += uint64(len(s))
+	}
+
+	// 哈希表条目：键和分数值
+	size += uint64(unsafe.Sizeof(ele)) + uint64(unsafe.Sizeof(score))
+
+	// 载荷映射条目：键和接口值
+	if payload, ok := z.payloads[ele]; ok {
+		size += uint64(unsafe.Sizeof(ele)) + uint64(unsafe.Sizeof(payload))
+	}
+
+	return size, true
+}
+
+// ErrInvalidMaxLevel 表示指定的最大层级超出允许范围。
+var ErrInvalidMaxLevel = errors.New("zset: max level must be in [1, 64]")
+
+// RebuildWithMaxLevel 以新的最大层级重建跳跃表。
+// maxLevel: 新的最大层级，取值范围 [1, 64]，可以低于 SKIPLIST_MAXLEVEL。
+// 重建后该 ZSet 的后续插入也受此上限约束；较低的上限减少每个节点的内存，但会略微增加查找开销。
+// maxLevel 超出范围时返回 ErrInvalidMaxLevel 且不修改集合。
+func (z *ZSet[M]) RebuildWithMaxLevel(maxLevel int) error {
+	z.lock()
+	defer z.unlock()
+
+	if maxLevel < 1 || maxLevel > maxLevelLimit {
+		return ErrInvalidMaxLevel
+	}
+
+	// 按原有顺序将所有节点插入新的跳跃表
+	zsl := createSkiplist(maxLevel, z.zsl.less)
+	zsl.rand = z.zsl.rand
+	zsl.p = z.zsl.p
+	for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		zsl.insert(x.score, x.ele)
+	}
+	z.zsl = zsl
+
+	return nil
+}
+
+// BucketSums 将分数区间 [min, max] 等分为 bins 个桶，统计每个桶的元素数量和分数之和。
+// min: 分数范围的最小值。
+// max: 分数范围的最大值。
+// bins: 桶的数量。
+// 分数为 score 的元素落入第 floor((score-min)/(max-min)*bins) 个桶，score == max 的元素归入最后一个桶。
+// 返回每个桶的统计结果；bins 不为正、max <= min、边界为 NaN 或区间宽度不是有限值（如边界为 ±Inf）时返回 nil。
+func (z *ZSet[M]) BucketSums(min, max float64, bins int) []struct {
+	Count uint64
+	Sum   float64
+} {
+	z.rlock()
+	defer z.runlock()
+
+	// 宽度为无穷时桶下标会算出 NaN 或 ±Inf，无法落入任何桶
+	if bins <= 0 || !(max > min) || math.IsInf(max-min, 0) {
+		return nil
+	}
+
+	result := make([]struct {
+		Count uint64
+		Sum   float64
+	}, bins)
+
+	// 跳到最小分数位置
+	x := z.zsl.header
+	for i := z.zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.score < min {
+			x = x.level[i].forward
+		}
+	}
+
+	// 遍历范围内的元素，按公式分配到桶中
+	width := max - min
+	for x = x.level[0].forward; x != nil && x.score <= max; x = x.level[0].forward {
+		idx := int((x.score - min) / width * float64(bins))
+		if idx >= bins {
+			idx = bins - 1
+		}
+		result[idx].Count++
+		result[idx].Sum += x.score
+	}
+
+	return result
+}
+
+// Histogram 按分数边界统计每个区间内的元素数量。
+// edges: 区间边界，必须升序且不含 NaN；第 i 个桶为 [edges[i], edges[i+1])，共 len(edges)-1 个桶。
+// 分数低于 edges[0] 或不低于最后一个边界的元素不计入任何桶。
+// 从第一个边界开始沿跳跃表顺序遍历一次，O(log n + m)，m 为落入桶中的元素数量。
+// 返回每个桶的元素数量；边界少于两个或不合法时返回 nil。
+func (z *ZSet[M]) Histogram(edges []float64) []int64 {
+	z.rlock()
+	defer z.runlock()
+
+	// 校验边界
+	if len(edges) < 2 {
+		return nil
+	}
+	for i, e := range edges {
+		if math.IsNaN(e) {
+			return nil
+		}
+		if i > 0 && e < edges[i-1] {
+			return nil
+		}
+	}
+
+	result := make([]int64, len(edges)-1)
+	last := edges[len(edges)-1]
+	idx := 0
+	for x := z.zsl.lastBelow(edges[0], false).level[0].forward; x != nil && x.score < last; x = x.level[0].forward {
+		// 分数有序，桶下标只会单调前进
+		for x.score >= edges[idx+1] {
+			idx++
+		}
+		result[idx]++
+	}
+
+	return result
+}
+
+// deleteRangeByRank 删除跳跃表中排名位于 [start, end] 的节点，并同步删除哈希表中的元素。
+// start: 起始排名（从 1 开始）。
+// end: 结束排名（从 1 开始，包含）。
+// dict: 需要同步删除元素的哈希表。
+// payloads: 需要同步删除元素的载荷映射，可以为 nil。
+// 返回删除的节点数量。
+func (sl *skiplist[M]) deleteRangeByRank(start, end uint64, dict map[M]float64, payloads map[M]any) uint64 {
+	var updateBuf [maxLevelLimit]*skiplistNode[M]
+	update := updateBuf[:sl.maxLevel]
+	var traversed, removed uint64 = 0, 0
+
+	// 查找起始排名之前的节点
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && traversed+x.level[i].span < start {
+			traversed += x.level[i].span
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	// 逐个删除区间内的节点
+	traversed++
+	x = x.level[0].forward
+	for x != nil && traversed <= end {
+		next := x.level[0].forward
+		sl.deleteNode(x, update)
+		delete(dict, x.ele)
+		delete(payloads, x.ele)
+		sl.freeNode(x)
+		removed++
+		traversed++
+		x = next
+	}
+
+	return removed
+}
+
+// RemoveRangeByRank 删除排名位于 [start, stop] 的所有元素，语义同 Redis ZREMRANGEBYRANK。
+// start: 起始排名（从 0 开始），负数表示从末尾倒数。
+// stop: 结束排名（包含），负数表示从末尾倒数。
+// 返回删除的元素数量。
+func (z *ZSet[M]) RemoveRangeByRank(start, stop int64) int {
+	z.lock()
+	defer z.unlock()
+
+	start, stop, ok := normalizeRange(start, stop, z.zsl.length)
+	if !ok {
+		return 0
+	}
+
+	return int(z.zsl.deleteRangeByRank(uint64(start)+1, uint64(stop)+1, z.dict, z.payloads))
+}
+
+// TrimToSize 删除多余的元素，使集合最多保留 maxLen 个元素，适合限制排行榜的长度。
+// 多余的元素位于跳跃表的一端，定位后按第 0 层连续删除，O(log n + k)，k 为删除的元素数量。
+// maxLen: 保留的最大元素数量，0 表示清空集合。
+// keepHighest: 为 true 时保留分数最高的元素，从头部删除分数最低的元素；为 false 时保留分数最低的元素，从尾部删除。
+// 返回删除的元素数量。
+func (z *ZSet[M]) TrimToSize(maxLen uint64, keepHighest bool) int {
+	z.lock()
+	defer z.unlock()
+
+	length := z.zsl.length
+	if length <= maxLen {
+		return 0
+	}
+
+	if keepHighest {
+		return int(z.zsl.deleteRangeByRank(1, length-maxLen, z.dict, z.payloads))
+	}
+	return int(z.zsl.deleteRangeByRank(maxLen+1, length, z.dict, z.payloads))
+}
+
+// deleteRangeByScore 删除跳跃表中分数位于 [min, max] 的节点，并同步删除哈希表中的元素。
+// min: 分数范围的最小值。
+// max: 分数范围的最大值。
+// dict: 需要同步删除元素的哈希表。
+// payloads: 需要同步删除元素的载荷映射，可以为 nil。
+// 返回删除的节点数量。
+func (sl *skiplist[M]) deleteRangeByScore(min, max float64, dict map[M]float64, payloads map[M]any) uint64 {
+	var updateBuf [maxLevelLimit]*skiplistNode[M]
+	update := updateBuf[:sl.maxLevel]
+	var removed uint64 = 0
+
+	// 查找最后一个分数小于 min 的节点
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.score < min {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	// 逐个删除区间内的节点
+	x = x.level[0].forward
+	for x != nil && x.score <= max {
+		next := x.level[0].forward
+		sl.deleteNode(x, update)
+		delete(dict, x.ele)
+		delete(payloads, x.ele)
+		sl.freeNode(x)
+		removed++
+		x = next
+	}
+
+	return removed
+}
+
+// RemoveRangeByScore 删除分数位于 [min, max] 的所有元素，语义同 Redis ZREMRANGEBYSCORE。
+// min: 分数范围的最小值。
+// max: 分数范围的最大值。
+// 返回删除的元素数量，min > max 或任一端为 NaN 时不删除任何元素。
+func (z *ZSet[M]) RemoveRangeByScore(min, max float64) int {
+	z.lock()
+	defer z.unlock()
+
+	if !(min <= max) {
+		return 0
+	}
+
+	return int(z.zsl.deleteRangeByScore(min, max, z.dict, z.payloads))
+}
+
+// Count 统计分数位于 [min, max] 的元素数量，语义同 Redis ZCOUNT。
+// min: 分数范围的最小值。
+// max: 分数范围的最大值。
+// 通过跳跃表跨度计算，复杂度为 O(log n)，不分配结果切片；min > max 或任一端为 NaN 时返回 0。
+func (z *ZSet[M]) Count(min, max float64) int64 {
+	z.rlock()
+	defer z.runlock()
+
+	if !(min <= max) {
+		return 0
+	}
+
+	// 分数小于等于 max 的数量减去分数小于 min 的数量
+	return int64(z.zsl.countBelow(max, true) - z.zsl.countBelow(min, false))
+}
+
+// pop 删除并返回分数最低或最高的元素，调用方需持有写锁。
+// max: 为 true 时弹出分数最高的元素，否则弹出分数最低的元素。
+// 返回被弹出的元素、分数和是否弹出成功的标志。
+func (z *ZSet[M]) pop(max bool) (M, float64, bool) {
+	x := z.zsl.header.level[0].forward
+	if max {
+		x = z.zsl.tail
+	}
+	if x == nil {
+		var zero M
+		return zero, 0, false
+	}
+
+	ele, score := x.ele, x.score
+	z.remove(ele)
+	return ele, score, true
+}
+
+// PopMin 删除并返回分数最低的元素，语义同 Redis ZPOPMIN。
+// 返回被弹出的元素、分数和是否弹出成功的标志，集合为空时返回 ("", 0, false)。
+func (z *ZSet[M]) PopMin() (M, float64, bool) {
+	z.lock()
+	defer z.unlock()
+
+	return z.pop(false)
+}
+
+// PopMax 删除并返回分数最高的元素，语义同 Redis ZPOPMAX。
+// 返回被弹出的元素、分数和是否弹出成功的标志，集合为空时返回 ("", 0, false)。
+func (z *ZSet[M]) PopMax() (M, float64, bool) {
+	z.lock()
+	defer z.unlock()
+
+	return z.pop(true)
+}
+
+// PopMinIf 仅当分数最低的元素的分数不超过 maxScore 时将其删除并返回，适合按时间戳出队的延迟队列。
+// maxScore: 分数阈值（包含）。
+// 返回被弹出的元素、分数和是否弹出成功的标志；集合为空或最低分数大于 maxScore 时不修改集合并返回 ("", 0, false)。
+func (z *ZSet[M]) PopMinIf(maxScore float64) (M, float64, bool) {
+	z.lock()
+	defer z.unlock()
+
+	x := z.zsl.header.level[0].forward
+	if x == nil || !(x.score <= maxScore) {
+		var zero M
+		return zero, 0, false
+	}
+	return z.pop(false)
+}
+
+// PopMinN 删除并返回分数最低的至多 n 个元素，语义同 Redis ZPOPMIN count。
+// n: 要弹出的元素数量，集合元素不足时全部弹出，n <= 0 时不做任何修改。
+// 返回按弹出顺序（分数升序）排列的元素列表。
+func (z *ZSet[M]) PopMinN(n int64) []struct {
+	Member M
+	Score  float64
+} {
+	z.lock()
+	defer z.unlock()
+
+	return z.popN(n, false)
+}
+
+// PopMaxN 删除并返回分数最高的至多 n 个元素，语义同 Redis ZPOPMAX count。
+// n: 要弹出的元素数量，集合元素不足时全部弹出，n <= 0 时不做任何修改。
+// 返回按弹出顺序（分数降序）排列的元素列表。
+func (z *ZSet[M]) PopMaxN(n int64) []struct {
+	Member M
+	Score  float64
+} {
+	z.lock()
+	defer z.unlock()
+
+	return z.popN(n, true)
+}
+
+// popN 依次弹出至多 n 个元素，调用方需持有写锁。
+func (z *ZSet[M]) popN(n int64, max bool) []struct {
+	Member M
+	Score  float64
+} {
+	var result []struct {
+		Member M
+		Score  float64
+	}
+	if n <= 0 {
+		return result
+	}
+	if n > int64(z.zsl.length) {
+		n = int64(z.zsl.length)
+	}
+
+	result = make([]struct {
+		Member M
+		Score  float64
+	}, 0, n)
+	for i := int64(0); i < n; i++ {
+		ele, score, ok := z.pop(max)
+		if !ok {
+			break
+		}
+		result = append(result, struct {
+			Member M
+			Score  float64
+		}{
+			Member: ele,
+			Score:  score,
+		})
+	}
+	return result
+}
+
+// AddBatch 批量添加或更新元素。
+// members: 要添加的元素及其分数。
+// 返回新添加（而非更新）的元素数量。
+func (z *ZSet[M]) AddBatch(members map[M]float64) int {
+	z.lock()
+	defer z.unlock()
+
+	added := 0
+	for ele, score := range members {
+		if z.add(ele, score) {
+			added++
+		}
+	}
+	return added
+}
+
+// IncrByBatch 批量增加多个元素的分数，在一次写锁内完成，等价于逐个调用 IncrBy。
+// deltas: 元素到分数增量的映射，不存在的元素视为分数为 0 并插入；结果为 NaN 的元素不做修改。
+func (z *ZSet[M]) IncrByBatch(deltas map[M]float64) {
+	z.lock()
+	defer z.unlock()
+
+	for ele, delta := range deltas {
+		z.add(ele, z.dict[ele]+delta)
+	}
+}
+
+// ReplaceAll 以 members 重建集合，丢弃原有的全部元素和载荷。
+// 整个替换在一次写锁内完成，并发安全的集合上其他 goroutine 只会看到替换前或替换后的完整内容。
+// 重建沿用原集合的最大层级、排序规则和随机源。
+// members: 新的元素及其分数，分数为 NaN 的元素会被忽略。
+func (z *ZSet[M]) ReplaceAll(members map[M]float64) {
+	z.lock()
+	defer z.unlock()
+
+	if err := z.reset(); err != nil {
+		return
+	}
+	z.dict = make(map[M]float64, len(members))
+	for ele, score := range members {
+		z.add(ele, score)
+	}
+}
+
+// RangeByRank 获取排名位于 [start, stop] 的元素，语义同 Redis ZRANGE。
+// start: 起始排名（从 0 开始），负数表示从末尾倒数。
+// stop: 结束排名（包含），负数表示从末尾倒数。
+// reverse: 是否按降序排名。
+// 超出范围的排名会被截断；start > stop 时返回空列表。
+func (z *ZSet[M]) RangeByRank(start, stop int64, reverse bool) []struct {
+	Member M
+	Score  float64
+} {
+	z.rlock()
+	defer z.runlock()
+
+	var result []struct {
+		Member M
+		Score  float64
+	}
+	// 截断后的窗口大小已知，一次分配到位
+	if first, last, ok := normalizeRange(start, stop, z.zsl.length); ok {
+		result = make([]struct {
+			Member M
+			Score  float64
+		}, 0, last-first+1)
+	}
+	z.walkRank(start, stop, reverse, func(x *skiplistNode[M], _ int64) bool {
+		result = append(result, struct {
+			Member M
+			Score  float64
+		}{
+			Member: x.ele,
+			Score:  x.score,
+		})
+		return true
+	})
+	return result
+}
+
+// RevRange 按降序获取排名位于 [start, stop] 的元素，语义同 Redis ZREVRANGE。
+// start: 起始排名，0 表示分数最高的元素，负数表示从末尾倒数。
+// stop: 结束排名（包含），负数表示从末尾倒数。
+// 返回按分数降序排列的元素列表，等价于 RangeByRank(start, stop, true)。
+func (z *ZSet[M]) RevRange(start, stop int64) []struct {
+	Member M
+	Score  float64
+} {
+	return z.RangeByRank(start, stop, true)
+}
+
+// RevRangeByRank 按降序获取排名位于 [start, stop] 的元素，与 RevRange 相同，命名与 RangeByRank 对应。
+// 降序排名在同一次读锁内按当时的长度换算，多个元素的结果来自集合的同一状态；
+// 逐个调用 GetByRank(rank, true) 时每次调用各自换算，调用之间集合长度变化会使结果错位。
+// start: 起始排名，0 表示分数最高的元素，负数表示从末尾倒数。
+// stop: 结束排名（包含），负数表示从末尾倒数。
+// 返回按分数降序排列的元素列表。
+func (z *ZSet[M]) RevRangeByRank(start, stop int64) []struct {
+	Member M
+	Score  float64
+} {
+	return z.RangeByRank(start, stop, true)
+}
+
+// RangeByRankMembers 获取排名位于 [start, stop] 的元素，不包含分数，相当于不带 WITHSCORES 的 Redis ZRANGE。
+// start: 起始排名（从 0 开始），负数表示从末尾倒数。
+// stop: 结束排名（包含），负数表示从末尾倒数。
+// reverse: 是否按降序排名。
+// 返回与 RangeByRank 顺序一致的元素列表。
+func (z *ZSet[M]) RangeByRankMembers(start, stop int64, reverse bool) []M {
+	z.rlock()
+	defer z.runlock()
+
+	var result []M
+	if first, last, ok := normalizeRange(start, stop, z.zsl.length); ok {
+		result = make([]M, 0, last-first+1)
+	}
+	z.walkRank(start, stop, reverse, func(x *skiplistNode[M], _ int64) bool {
+		result = append(result, x.ele)
+		return true
+	})
+	return result
+}
+
+// walkRank 按顺序对排名位于 [start, stop] 的节点调用 fn，调用方需持有读锁。
+// start、stop 支持负数索引，超出范围的排名会被截断。
+// fn 的 rank 参数为节点的升序排名（从 0 开始），与 reverse 无关；fn 返回 false 时停止遍历。
+func (z *ZSet[M]) walkRank(start, stop int64, reverse bool, fn func(x *skiplistNode[M], rank int64) bool) {
+	start, stop, ok := normalizeRange(start, stop, z.zsl.length)
+	if !ok {
+		return
+	}
+
+	// 定位起始节点
+	var x *skiplistNode[M]
+	if reverse {
+		x = z.zsl.getElementByRank(z.zsl.length - uint64(start))
+	} else {
+		x = z.zsl.getElementByRank(uint64(start) + 1)
+	}
+
+	// 沿前向或后向指针遍历
+	for i := start; i <= stop && x != nil; i++ {
+		rank := i
+		if reverse {
+			rank = int64(z.zsl.length) - 1 - i
+		}
+		if !fn(x, rank) {
+			return
+		}
+
+		if reverse {
+			x = x.backward
+		} else {
+			x = x.level[0].forward
+		}
+	}
+}
+
+// WalkRange 按顺序对排名位于 [start, stop] 的元素调用 fn，不构建中间切片，适合流式导出。
+// start: 起始排名（从 0 开始），负数表示从末尾倒数。
+// stop: 结束排名（包含），负数表示从末尾倒数。
+// reverse: 是否按降序排名，start 和 stop 按对应方向解释。
+// fn: 对每个元素调用的函数，rank 为元素的升序排名（降序遍历时依次递减）；返回 false 时停止遍历，fn 中不能修改该集合。
+func (z *ZSet[M]) WalkRange(start, stop int64, reverse bool, fn func(member M, score float64, rank int64) bool) {
+	z.rlock()
+	defer z.runlock()
+
+	z.walkRank(start, stop, reverse, func(x *skiplistNode[M], rank int64) bool {
+		return fn(x.ele, x.score, rank)
+	})
+}
+
+// RangeByScoreFunc 按分数升序对分数位于 [min, max] 的元素调用 fn，是 RangeByScore 不分配内存的流式版本。
+// min: 分数范围的最小值（包含）。
+// max: 分数范围的最大值（包含）。
+// fn: 对每个元素调用的函数，返回 false 时停止遍历；fn 中不能修改该集合。
+// min 大于 max 时不调用 fn。
+func (z *ZSet[M]) RangeByScoreFunc(min, max float64, fn func(member M, score float64) bool) {
+	z.rlock()
+	defer z.runlock()
+
+	if !(min <= max) {
+		return
+	}
+
+	for x := z.zsl.lastBelow(min, false).level[0].forward; x != nil && x.score <= max; x = x.level[0].forward {
+		if !fn(x.ele, x.score) {
+			return
+		}
+	}
+}
+
+// ForEach 按分数顺序遍历 ZSet 中的元素，不分配额外内存。
+// reverse: 为 true 时沿后向指针按降序遍历，否则沿前向指针按升序遍历。
+// fn: 对每个元素调用的函数，返回 false 时停止遍历；fn 中不能修改该集合。
+func (z *ZSet[M]) ForEach(reverse bool, fn func(member M, score float64) bool) {
+	z.rlock()
+	defer z.runlock()
+
+	x := z.zsl.header.level[0].forward
+	if reverse {
+		x = z.zsl.tail
+	}
+
+	for x != nil {
+		if !fn(x.ele, x.score) {
+			return
+		}
+
+		if reverse {
+			x = x.backward
+		} else {
+			x = x.level[0].forward
+		}
+	}
+}
+
+// All 返回按分数升序遍历元素的迭代器，可用于 for member, score := range z.All()。
+// 循环体中不能修改该集合。
+func (z *ZSet[M]) All() iter.Seq2[M, float64] {
+	return func(yield func(M, float64) bool) {
+		z.ForEach(false, yield)
+	}
+}
+
+// AllReverse 返回按分数降序遍历元素的迭代器。
+// 循环体中不能修改该集合。
+func (z *ZSet[M]) AllReverse() iter.Seq2[M, float64] {
+	return func(yield func(M, float64) bool) {
+		z.ForEach(true, yield)
+	}
+}
+
+// RandomMember 随机返回一个元素，语义同 Redis ZRANDMEMBER。
+// 先均匀地随机选取排名，再通过跳跃表跨度定位，每个元素被选中的概率相同。
+// 返回元素、分数和是否存在的标志，集合为空时返回 false。
+func (z *ZSet[M]) RandomMember() (M, float64, bool) {
+	z.rlock()
+	defer z.runlock()
+
+	if z.zsl.length == 0 {
+		var zero M
+		return zero, 0, false
+	}
+
+	x := z.zsl.getElementByRank(uint64(randInt63n(int64(z.zsl.length))) + 1)
+	return x.ele, x.score, true
+}
+
+// WeightedSample 以分数为权重随机返回一个元素，元素被选中的概率与其分数成正比。
+// 先累加所有权重，再在 [0, 总权重) 内均匀取值，沿第 0 层累加权重找到对应元素，O(n)。
+// 负分数的元素权重视为 0，不会被选中。
+// 返回元素、分数和是否选中的标志；集合为空、总权重为 0 或累加溢出为 +Inf 时返回 false。
+func (z *ZSet[M]) WeightedSample() (M, float64, bool) {
+	z.rlock()
+	defer z.runlock()
+
+	var zero M
+	var total float64
+	for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		if x.score > 0 {
+			total += x.score
+		}
+	}
+	if total <= 0 || math.IsInf(total, 1) {
+		return zero, 0, false
+	}
+
+	target := randFloat64() * total
+	var sum float64
+	var last *skiplistNode[M]
+	for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		if x.score <= 0 {
+			continue
+		}
+		sum += x.score
+		if target < sum {
+			return x.ele, x.score, true
+		}
+		last = x
+	}
+
+	// 浮点累加误差可能使 target 落在末尾之外，归入最后一个正权重元素
+	return last.ele, last.score, true
+}
+
+// RandomMembers 随机返回多个元素，语义同 Redis ZRANDMEMBER count [WITHSCORES]。
+// count: 为正数时返回互不相同的元素，最多返回全部元素；为负数时允许重复，恰好返回 -count 个元素。
+// withScores: 是否填充 Score 字段，为 false 时 Score 为 0。
+// 每次抽取都是对排名的均匀抽样：count 为负数时各次抽取相互独立；
+// count 为正数时使用 Floyd 算法抽取不重复的排名，任意大小为 count 的子集被选中的概率相同，结果按排名升序排列。
+func (z *ZSet[M]) RandomMembers(count int64, withScores bool) []struct {
+	Member M
+	Score  float64
+} {
+	z.rlock()
+	defer z.runlock()
+
+	var result []struct {
+		Member M
+		Score  float64
+	}
+
+	n := int64(z.zsl.length)
+	if n == 0 || count == 0 {
+		return result
+	}
+
+	appendRank := func(rank int64) {
+		x := z.zsl.getElementByRank(uint64(rank) + 1)
+		entry := struct {
+			Member M
+			Score  float64
+		}{Member: x.ele}
+		if withScores {
+			entry.Score = x.score
+		}
+		result = append(result, entry)
+	}
+
+	// 允许重复：独立抽取 -count 次
+	if count < 0 {
+		for i := int64(0); i < -count; i++ {
+			appendRank(randInt63n(n))
+		}
+		return result
+	}
+
+	// 不允许重复：使用 Floyd 算法抽取 count 个不同的排名
+	if count > n {
+		count = n
+	}
+	chosen := make(map[int64]struct{}, count)
+	ranks := make([]int64, 0, count)
+	for j := n - count; j < n; j++ {
+		r := randInt63n(j + 1)
+		if _, exists := chosen[r]; exists {
+			r = j
+		}
+		chosen[r] = struct{}{}
+		ranks = append(ranks, r)
+	}
+
+	slices.Sort(ranks)
+	for _, rank := range ranks {
+		appendRank(rank)
+	}
+	return result
+}
+
+// Members 按分数顺序返回全部元素，不包含分数。
+// reverse: 是否按降序返回。
+// 返回元素切片，集合为空时返回空切片。
+func (z *ZSet[M]) Members(reverse bool) []M {
+	z.rlock()
+	defer z.runlock()
+
+	members := make([]M, 0, z.zsl.length)
+	x := z.zsl.header.level[0].forward
+	if reverse {
+		x = z.zsl.tail
+	}
+
+	for x != nil {
+		members = append(members, x.ele)
+		if reverse {
+			x = x.backward
+		} else {
+			x = x.level[0].forward
+		}
+	}
+	return members
+}
+
+// Scores 按分数顺序返回全部分数，不包含元素，适合直接做统计计算。
+// reverse: 是否按降序返回。
+// 返回分数切片，集合为空时返回空切片。
+func (z *ZSet[M]) Scores(reverse bool) []float64 {
+	z.rlock()
+	defer z.runlock()
+
+	scores := make([]float64, 0, z.zsl.length)
+	x := z.zsl.header.level[0].forward
+	if reverse {
+		x = z.zsl.tail
+	}
+
+	for x != nil {
+		scores = append(scores, x.score)
+		if reverse {
+			x = x.backward
+		} else {
+			x = x.level[0].forward
+		}
+	}
+	return scores
+}
+
+// Clear 清空集合中的全部元素，复用已分配的哈希表和跳跃表头节点。
+// 清空后的集合与新创建的集合状态相同，保留原有的最大层级和排序规则。
+func (z *ZSet[M]) Clear() {
+	z.lock()
+	defer z.unlock()
+
+	z.clear(false)
+}
+
+// clear 清空集合中的全部元素，调用方需持有写锁。
+// recycle: 是否将节点放入空闲列表供之后的插入复用，为 true 时需要遍历全部节点，O(n)。
+func (z *ZSet[M]) clear(recycle bool) {
+	if z.zsl == nil {
+		z.reset()
+		return
+	}
+
+	clear(z.dict)
+	clear(z.payloads)
+	sl := z.zsl
+	if recycle {
+		for x := sl.header.level[0].forward; x != nil; {
+			next := x.level[0].forward
+			sl.freeNode(x)
+			x = next
+		}
+	}
+	for j := range sl.header.level {
+		sl.header.level[j].forward = nil
+		sl.header.level[j].span = 0
+	}
+	sl.tail = nil
+	sl.length = 0
+	sl.level = 1
+}
+
+// Clone 返回集合的深拷贝，拷贝与原集合互不影响。
+// 拷贝沿用原集合的最大层级和排序规则，原集合并发安全时拷贝也是并发安全的。
+func (z *ZSet[M]) Clone() *ZSet[M] {
+	z.rlock()
+	defer z.runlock()
+
+	dst := z.emptyLike()
+	if z.mu != nil {
+		dst.mu = &sync.RWMutex{}
+	}
+	for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		dst.zsl.insert(x.score, x.ele)
+		dst.dict[x.ele] = x.score
+	}
+	dst.payloads = maps.Clone(z.payloads)
+	return dst
+}
+
+// First 返回分数最低的元素，不移除该元素，O(1)。
+// 返回元素、分数和是否存在的标志，集合为空时返回 false。
+func (z *ZSet[M]) First() (M, float64, bool) {
+	z.rlock()
+	defer z.runlock()
+
+	x := z.zsl.header.level[0].forward
+	if x == nil {
+		var zero M
+		return zero, 0, false
+	}
+	return x.ele, x.score, true
+}
+
+// Last 返回分数最高的元素，不移除该元素，O(1)。
+// 返回元素、分数和是否存在的标志，集合为空时返回 false。
+func (z *ZSet[M]) Last() (M, float64, bool) {
+	z.rlock()
+	defer z.runlock()
+
+	x := z.zsl.tail
+	if x == nil {
+		var zero M
+		return zero, 0, false
+	}
+	return x.ele, x.score, true
+}
+
+// MinScore 返回集合中的最低分数，O(1)。
+// 返回最低分数和是否存在的标志，集合为空时返回 false。
+func (z *ZSet[M]) MinScore() (float64, bool) {
+	z.rlock()
+	defer z.runlock()
+
+	if x := z.zsl.header.level[0].forward; x != nil {
+		return x.score, true
+	}
+	return 0, false
+}
+
+// MaxScore 返回集合中的最高分数，O(1)。
+// 返回最高分数和是否存在的标志，集合为空时返回 false。
+func (z *ZSet[M]) MaxScore() (float64, bool) {
+	z.rlock()
+	defer z.runlock()
+
+	if x := z.zsl.tail; x != nil {
+		return x.score, true
+	}
+	return 0, false
+}
+
+// ToMap 返回元素到分数映射的副本，修改返回的 map 不会影响集合。
+func (z *ZSet[M]) ToMap() map[M]float64 {
+	z.rlock()
+	defer z.runlock()
+
+	return maps.Clone(z.dict)
+}
+
+// Quantile 获取升序排列中位于分位点 q 的元素分数（最近排名法，不插值）。
+// q: 分位点，超出 [0, 1] 时截断到该范围；0 对应最低分，1 对应最高分。
+// 返回排名 round(q*(Len()-1)) 处元素的分数，集合为空或 q 为 NaN 时返回 false。
+func (z *ZSet[M]) Quantile(q float64) (score float64, ok bool) {
+	z.rlock()
+	defer z.runlock()
+
+	if z.zsl.length == 0 || math.IsNaN(q) {
+		return 0, false
+	}
+	q = math.Max(0, math.Min(1, q))
+
+	rank := uint64(math.Round(q * float64(z.zsl.length-1)))
+	x := z.zsl.getElementByRank(rank + 1)
+	if x == nil {
+		return 0, false
+	}
+	return x.score, true
+}
+
+// RemoveAll 从集合中删除多个元素，不存在的元素会被跳过。
+// members: 要删除的元素。
+// 返回实际删除的元素数量。
+func (z *ZSet[M]) RemoveAll(members ...M) int {
+	z.lock()
+	defer z.unlock()
+
+	removed := 0
+	for _, ele := range members {
+		if z.remove(ele) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// SubtractSet 从集合中删除所有同时存在于 other 中的元素，相当于原地计算差集。
+// other: 提供要删除元素的集合，不会被修改；可以是集合自身，此时清空集合。
+// 返回实际删除的元素数量。
+func (z *ZSet[M]) SubtractSet(other *ZSet[M]) int {
+	if other == nil {
+		return 0
+	}
+
+	unlock := lockInto(z, other)
+	defer unlock()
+
+	removed := 0
+	// 遍历较小的一方，range 期间删除 map 元素是安全的
+	if len(z.dict) <= len(other.dict) {
+		for ele := range z.dict {
+			if _, exists := other.dict[ele]; exists && z.remove(ele) {
+				removed++
+			}
+		}
+	} else {
+		for ele := range other.dict {
+			if z.remove(ele) {
+				removed++
+			}
+		}
+	}
+	return removed
+}
+
+// MergeAdd 将 other 中每个元素的分数累加到集合中，相当于原地计算 SUM 聚合的并集。
+// 集合中不存在的元素以 other 中的分数插入，已存在的元素累加后重新定位；累加结果为 NaN 的元素不做修改。
+// other: 提供分数的集合，不会被修改；可以是集合自身，此时所有分数翻倍。
+func (z *ZSet[M]) MergeAdd(other *ZSet[M]) {
+	if other == nil {
+		return
+	}
+
+	unlock := lockInto(z, other)
+	defer unlock()
+
+	// other 与 z 相同时只更新已有键的值，range 期间这样修改 map 是安全的
+	for ele, score := range other.dict {
+		z.add(ele, z.dict[ele]+score)
+	}
+}
+
+// MoveTo 将元素连同分数和载荷从集合中移除并添加到 dst，两个集合按地址顺序加写锁，整个移动是原子的。
+// dst: 目标集合，元素已存在时覆盖其分数和载荷；与集合自身相同时不做修改。
+// ele: 要移动的元素。
+// 返回元素是否存在于集合中，不存在或 dst 为 nil 时不做任何修改并返回 false。
+func (z *ZSet[M]) MoveTo(dst *ZSet[M], ele M) bool {
+	if dst == nil {
+		return false
+	}
+
+	unlock := lockAll(z, dst)
+	defer unlock()
+
+	score, exists := z.dict[ele]
+	if !exists {
+		return false
+	}
+	if dst == z {
+		return true
+	}
+
+	payload := z.payloads[ele]
+	z.remove(ele)
+	if x, _ := dst.upsert(ele, score); x != nil {
+		dst.setPayload(ele, payload)
+	}
+	return true
+}
+
+// Rename 将元素改名，保留其分数和载荷，适合用户修改昵称但保留排名的场景。
+// 新名称按排序规则重新参与同分元素的先后比较，因此同分元素之间的排名可能变化。
+// oldEle: 原元素。
+// newEle: 新元素，已存在时不做任何修改，避免覆盖另一个元素的分数；与 oldEle 相同时视为成功且不做修改。
+// 返回是否改名成功，oldEle 不存在或 newEle 已存在时返回 false。
+func (z *ZSet[M]) Rename(oldEle, newEle M) bool {
+	z.lock()
+	defer z.unlock()
+
+	score, exists := z.dict[oldEle]
+	if !exists {
+		return false
+	}
+	if oldEle == newEle {
+		return true
+	}
+	if _, taken := z.dict[newEle]; taken {
+		return false
+	}
+
+	payload := z.payloads[oldEle]
+	z.remove(oldEle)
+	if x, _ := z.upsert(newEle, score); x != nil {
+		z.setPayload(newEle, payload)
+	}
+	return true
+}
+
+// Filter 按分数升序遍历集合，返回满足条件的元素。
+// pred: 判断元素是否被选中的函数，pred 中不能修改该集合。
+// 返回按分数升序排列的选中元素列表。
+func (z *ZSet[M]) Filter(pred func(member M, score float64) bool) []struct {
+	Member M
+	Score  float64
+} {
+	z.rlock()
+	defer z.runlock()
+
+	var result []struct {
+		Member M
+		Score  float64
+	}
+	for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		if pred(x.ele, x.score) {
+			result = append(result, struct {
+				Member M
+				Score  float64
+			}{
+				Member: x.ele,
+				Score:  x.score,
+			})
+		}
+	}
+	return result
+}
+
+// Equal 判断两个集合的逻辑内容是否相同，即元素数量相同且每个元素的分数都相等。
+// other: 要比较的集合。
+// 只比较元素和分数，忽略跳跃表的层级结构和插入顺序；两者都为 nil 时返回 true。
+func (z *ZSet[M]) Equal(other *ZSet[M]) bool {
+	if z == nil || other == nil {
+		return z == other
+	}
+
+	unlock := rlockAll(z, other)
+	defer unlock()
+
+	return maps.Equal(z.dict, other.dict)
+}
+
+// stringMaxEntries 是 String 输出的最大元素数量，超出部分被截断。
+const stringMaxEntries = 100
+
+// String 按分数升序输出集合内容，格式为 [a:1.0 b:2.5 c:3.0]，用于调试和测试失败信息。
+// 超过 stringMaxEntries 个元素时截断，并在末尾注明剩余元素数量，如 [a:1.0 ... (+150 more)]。
+func (z *ZSet[M]) String() string {
+	z.rlock()
+	defer z.runlock()
+
+	var b strings.Builder
+	b.WriteByte('[')
+	n := 0
+	for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		if n == stringMaxEntries {
+			fmt.Fprintf(&b, " ... (+%d more)", z.zsl.length-uint64(n))
+			break
+		}
+		if n > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprint(&b, x.ele)
+		b.WriteByte(':')
+		b.WriteString(formatScore(x.score))
+		n++
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// formatScore 以最短的精确形式格式化分数，整数分数保留一位小数（如 1.0）。
+func formatScore(score float64) string {
+	s := strconv.FormatFloat(score, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
 }
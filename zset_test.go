@@ -2,7 +2,13 @@ package zset
 
 import (
 	"github.com/stretchr/testify/assert"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"unsafe"
 )
 
 func TestZSet_Add(t *testing.T) {
@@ -90,7 +96,7 @@ func TestZSet_Add(t *testing.T) {
 func TestZSet_Remove(t *testing.T) {
 	tests := []struct {
 		name     string
-		setup    func() *ZSet
+		setup    func() *ZSet[string]
 		ele      string
 		want     bool
 		wantDict map[string]float64
@@ -98,7 +104,7 @@ func TestZSet_Remove(t *testing.T) {
 	}{
 		{
 			name: "remove existing element",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
 				z.Add("b", 2.0)
@@ -111,7 +117,7 @@ func TestZSet_Remove(t *testing.T) {
 		},
 		{
 			name: "remove non-existing element",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
 				return z
@@ -123,7 +129,7 @@ func TestZSet_Remove(t *testing.T) {
 		},
 		{
 			name: "remove from empty set",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				return NewZSet()
 			},
 			ele:      "a",
@@ -133,7 +139,7 @@ func TestZSet_Remove(t *testing.T) {
 		},
 		{
 			name: "remove last element",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
 				return z
@@ -159,14 +165,14 @@ func TestZSet_Remove(t *testing.T) {
 func TestZSet_Score(t *testing.T) {
 	tests := []struct {
 		name           string
-		setup          func() *ZSet
+		setup          func() *ZSet[string]
 		ele            string
 		expectedScore  float64
 		expectedExists bool
 	}{
 		{
 			name: "element exists",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("member1", 10.5)
 				return z
@@ -177,7 +183,7 @@ func TestZSet_Score(t *testing.T) {
 		},
 		{
 			name: "element does not exist",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("member1", 10.5)
 				return z
@@ -188,7 +194,7 @@ func TestZSet_Score(t *testing.T) {
 		},
 		{
 			name: "empty set",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				return NewZSet()
 			},
 			ele:            "any",
@@ -197,7 +203,7 @@ func TestZSet_Score(t *testing.T) {
 		},
 		{
 			name: "multiple elements",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("member1", 10.5)
 				z.Add("member2", 20.0)
@@ -210,7 +216,7 @@ func TestZSet_Score(t *testing.T) {
 		},
 		{
 			name: "zero score",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("zero", 0.0)
 				return z
@@ -221,7 +227,7 @@ func TestZSet_Score(t *testing.T) {
 		},
 		{
 			name: "negative score",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("negative", -5.5)
 				return z
@@ -245,14 +251,14 @@ func TestZSet_Score(t *testing.T) {
 func TestZSet_Rank(t *testing.T) {
 	tests := []struct {
 		name     string
-		setup    func() *ZSet
+		setup    func() *ZSet[string]
 		ele      string
 		reverse  bool
 		expected int64
 	}{
 		{
 			name: "element exists - forward rank",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
 				z.Add("b", 2.0)
@@ -265,7 +271,7 @@ func TestZSet_Rank(t *testing.T) {
 		},
 		{
 			name: "element exists - reverse rank",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
 				z.Add("b", 2.0)
@@ -278,7 +284,7 @@ func TestZSet_Rank(t *testing.T) {
 		},
 		{
 			name: "element not exists",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
 				z.Add("b", 2.0)
@@ -290,7 +296,7 @@ func TestZSet_Rank(t *testing.T) {
 		},
 		{
 			name: "empty set",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				return NewZSet()
 			},
 			ele:      "a",
@@ -299,7 +305,7 @@ func TestZSet_Rank(t *testing.T) {
 		},
 		{
 			name: "single element - forward rank",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
 				return z
@@ -310,7 +316,7 @@ func TestZSet_Rank(t *testing.T) {
 		},
 		{
 			name: "single element - reverse rank",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
 				return z
@@ -321,7 +327,7 @@ func TestZSet_Rank(t *testing.T) {
 		},
 		{
 			name: "duplicate scores - forward rank",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
 				z.Add("b", 1.0)
@@ -334,7 +340,7 @@ func TestZSet_Rank(t *testing.T) {
 		},
 		{
 			name: "duplicate scores - reverse rank",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
 				z.Add("b", 1.0)
@@ -356,10 +362,33 @@ func TestZSet_Rank(t *testing.T) {
 	}
 }
 
+func BenchmarkZSet_Rank(b *testing.B) {
+	const n = 100000
+	z := newBenchZSet(n)
+	members := make([]string, n)
+	for i := range members {
+		members[i] = "member" + strconv.Itoa(i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.Rank(members[i%n], false)
+	}
+}
+
+func BenchmarkZSet_RankMissing(b *testing.B) {
+	z := newBenchZSet(100000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.Rank("missing", false)
+	}
+}
+
 func TestZSet_GetByRank(t *testing.T) {
 	tests := []struct {
 		name      string
-		setup     func() *ZSet
+		setup     func() *ZSet[string]
 		rank      int64
 		reverse   bool
 		wantEle   string
@@ -368,7 +397,7 @@ func TestZSet_GetByRank(t *testing.T) {
 	}{
 		{
 			name: "empty set",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				return NewZSet()
 			},
 			rank:    0,
@@ -377,18 +406,99 @@ func TestZSet_GetByRank(t *testing.T) {
 		},
 		{
 			name: "rank out of range (negative)",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
+				z := NewZSet()
+				z.Add("a", 1.0)
+				return z
+			},
+			rank:    -2,
+			reverse: false,
+			wantOk:  false,
+		},
+		{
+			name: "rank out of range (negative reverse)",
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
+				z.Add("b", 2.0)
 				return z
 			},
+			rank:    -3,
+			reverse: true,
+			wantOk:  false,
+		},
+		{
+			name: "negative rank on empty set",
+			setup: func() *ZSet[string] {
+				return NewZSet()
+			},
 			rank:    -1,
 			reverse: false,
 			wantOk:  false,
 		},
+		{
+			name: "minus one forward is last",
+			setup: func() *ZSet[string] {
+				z := NewZSet()
+				z.Add("a", 1.0)
+				z.Add("b", 2.0)
+				z.Add("c", 3.0)
+				return z
+			},
+			rank:      -1,
+			reverse:   false,
+			wantEle:   "c",
+			wantScore: 3.0,
+			wantOk:    true,
+		},
+		{
+			name: "minus one reverse is lowest",
+			setup: func() *ZSet[string] {
+				z := NewZSet()
+				z.Add("a", 1.0)
+				z.Add("b", 2.0)
+				z.Add("c", 3.0)
+				return z
+			},
+			rank:      -1,
+			reverse:   true,
+			wantEle:   "a",
+			wantScore: 1.0,
+			wantOk:    true,
+		},
+		{
+			name: "minus len forward is first",
+			setup: func() *ZSet[string] {
+				z := NewZSet()
+				z.Add("a", 1.0)
+				z.Add("b", 2.0)
+				z.Add("c", 3.0)
+				return z
+			},
+			rank:      -3,
+			reverse:   false,
+			wantEle:   "a",
+			wantScore: 1.0,
+			wantOk:    true,
+		},
+		{
+			name: "minus len reverse is highest",
+			setup: func() *ZSet[string] {
+				z := NewZSet()
+				z.Add("a", 1.0)
+				z.Add("b", 2.0)
+				z.Add("c", 3.0)
+				return z
+			},
+			rank:      -3,
+			reverse:   true,
+			wantEle:   "c",
+			wantScore: 3.0,
+			wantOk:    true,
+		},
 		{
 			name: "rank out of range (too large)",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
 				return z
@@ -399,7 +509,7 @@ func TestZSet_GetByRank(t *testing.T) {
 		},
 		{
 			name: "valid rank forward",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
 				z.Add("b", 2.0)
@@ -414,7 +524,7 @@ func TestZSet_GetByRank(t *testing.T) {
 		},
 		{
 			name: "valid rank reverse",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
 				z.Add("b", 2.0)
@@ -429,7 +539,7 @@ func TestZSet_GetByRank(t *testing.T) {
 		},
 		{
 			name: "first element forward",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
 				z.Add("b", 2.0)
@@ -443,7 +553,7 @@ func TestZSet_GetByRank(t *testing.T) {
 		},
 		{
 			name: "first element reverse",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
 				z.Add("b", 2.0)
@@ -457,7 +567,7 @@ func TestZSet_GetByRank(t *testing.T) {
 		},
 		{
 			name: "last element forward",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
 				z.Add("b", 2.0)
@@ -471,7 +581,7 @@ func TestZSet_GetByRank(t *testing.T) {
 		},
 		{
 			name: "last element reverse",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1.0)
 				z.Add("b", 2.0)
@@ -501,7 +611,7 @@ func TestZSet_GetByRank(t *testing.T) {
 func TestRangeByScore(t *testing.T) {
 	tests := []struct {
 		name     string
-		setup    func() *ZSet
+		setup    func() *ZSet[string]
 		min      float64
 		max      float64
 		offset   int64
@@ -513,7 +623,7 @@ func TestRangeByScore(t *testing.T) {
 	}{
 		{
 			name: "empty set",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				return NewZSet()
 			},
 			min:    0,
@@ -527,7 +637,7 @@ func TestRangeByScore(t *testing.T) {
 		},
 		{
 			name: "single element in range",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 5)
 				return z
@@ -545,7 +655,7 @@ func TestRangeByScore(t *testing.T) {
 		},
 		{
 			name: "single element out of range",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 15)
 				return z
@@ -561,7 +671,7 @@ func TestRangeByScore(t *testing.T) {
 		},
 		{
 			name: "multiple elements with offset",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1)
 				z.Add("b", 2)
@@ -584,7 +694,7 @@ func TestRangeByScore(t *testing.T) {
 		},
 		{
 			name: "multiple elements with negative count",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1)
 				z.Add("b", 2)
@@ -606,7 +716,7 @@ func TestRangeByScore(t *testing.T) {
 		},
 		{
 			name: "multiple elements with partial range",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1)
 				z.Add("b", 2)
@@ -630,7 +740,7 @@ func TestRangeByScore(t *testing.T) {
 		},
 		{
 			name: "negative offset",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1)
 				z.Add("b", 2)
@@ -650,7 +760,7 @@ func TestRangeByScore(t *testing.T) {
 		},
 		{
 			name: "count larger than available elements",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1)
 				z.Add("b", 2)
@@ -670,7 +780,7 @@ func TestRangeByScore(t *testing.T) {
 		},
 		{
 			name: "min equals max",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("a", 1)
 				z.Add("b", 2)
@@ -704,19 +814,19 @@ func TestRangeByScore(t *testing.T) {
 func TestZSet_Len(t *testing.T) {
 	tests := []struct {
 		name     string
-		setup    func() *ZSet
+		setup    func() *ZSet[string]
 		expected uint64
 	}{
 		{
 			name: "empty set",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				return NewZSet()
 			},
 			expected: 0,
 		},
 		{
 			name: "single element",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("element1", 1.0)
 				return z
@@ -725,7 +835,7 @@ func TestZSet_Len(t *testing.T) {
 		},
 		{
 			name: "multiple elements",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("element1", 1.0)
 				z.Add("element2", 2.0)
@@ -736,7 +846,7 @@ func TestZSet_Len(t *testing.T) {
 		},
 		{
 			name: "after removal",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("element1", 1.0)
 				z.Add("element2", 2.0)
@@ -747,7 +857,7 @@ func TestZSet_Len(t *testing.T) {
 		},
 		{
 			name: "duplicate elements",
-			setup: func() *ZSet {
+			setup: func() *ZSet[string] {
 				z := NewZSet()
 				z.Add("element1", 1.0)
 				z.Add("element1", 2.0) // Should update score but not increase length
@@ -764,3 +874,3446 @@ func TestZSet_Len(t *testing.T) {
 		})
 	}
 }
+
+func TestChangedRanks(t *testing.T) {
+	type change = struct {
+		Member  string
+		OldRank int64
+		NewRank int64
+	}
+
+	tests := []struct {
+		name     string
+		setup    func() (*ZSet[string], *ZSet[string])
+		expected []change
+	}{
+		{
+			name: "both empty",
+			setup: func() (*ZSet[string], *ZSet[string]) {
+				return NewZSet(), NewZSet()
+			},
+			expected: nil,
+		},
+		{
+			name: "no changes",
+			setup: func() (*ZSet[string], *ZSet[string]) {
+				a, b := NewZSet(), NewZSet()
+				a.Add("a", 1)
+				a.Add("b", 2)
+				b.Add("a", 10)
+				b.Add("b", 20)
+				return a, b
+			},
+			expected: nil,
+		},
+		{
+			name: "swapped members",
+			setup: func() (*ZSet[string], *ZSet[string]) {
+				a, b := NewZSet(), NewZSet()
+				a.Add("a", 1)
+				a.Add("b", 2)
+				a.Add("c", 3)
+				b.Add("a", 1)
+				b.Add("b", 4)
+				b.Add("c", 3)
+				return a, b
+			},
+			expected: []change{
+				{"b", 1, 2},
+				{"c", 2, 1},
+			},
+		},
+		{
+			name: "appeared and disappeared",
+			setup: func() (*ZSet[string], *ZSet[string]) {
+				a, b := NewZSet(), NewZSet()
+				a.Add("a", 1)
+				a.Add("b", 2)
+				b.Add("a", 1)
+				b.Add("c", 3)
+				return a, b
+			},
+			expected: []change{
+				{"b", 1, -1},
+				{"c", -1, 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldSet, newSet := tt.setup()
+			assert.Equal(t, tt.expected, ChangedRanks(oldSet, newSet))
+		})
+	}
+}
+
+func TestZSet_Tiers(t *testing.T) {
+	type entry = struct {
+		Member string
+		Score  float64
+	}
+
+	setup := func() *ZSet[string] {
+		z := NewZSet()
+		z.Add("a", 1)
+		z.Add("b", 2)
+		z.Add("c", 3)
+		z.Add("d", 4)
+		z.Add("e", 5)
+		return z
+	}
+
+	tests := []struct {
+		name       string
+		boundaries []int64
+		reverse    bool
+		expected   [][]entry
+	}{
+		{
+			name:       "no boundaries",
+			boundaries: nil,
+			expected: [][]entry{
+				{{"a", 1}, {"b", 2}, {"c", 3}, {"d", 4}, {"e", 5}},
+			},
+		},
+		{
+			name:       "forward tiers",
+			boundaries: []int64{1, 3},
+			expected: [][]entry{
+				{{"a", 1}},
+				{{"b", 2}, {"c", 3}},
+				{{"d", 4}, {"e", 5}},
+			},
+		},
+		{
+			name:       "reverse tiers",
+			boundaries: []int64{1, 3},
+			reverse:    true,
+			expected: [][]entry{
+				{{"e", 5}},
+				{{"d", 4}, {"c", 3}},
+				{{"b", 2}, {"a", 1}},
+			},
+		},
+		{
+			name:       "empty tiers at edges",
+			boundaries: []int64{0, 2, 2, 5},
+			expected: [][]entry{
+				nil,
+				{{"a", 1}, {"b", 2}},
+				nil,
+				{{"c", 3}, {"d", 4}, {"e", 5}},
+				nil,
+			},
+		},
+		{
+			name:       "descending boundaries",
+			boundaries: []int64{3, 1},
+			expected:   nil,
+		},
+		{
+			name:       "boundary out of range",
+			boundaries: []int64{6},
+			expected:   nil,
+		},
+		{
+			name:       "negative boundary",
+			boundaries: []int64{-1},
+			expected:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := setup()
+			assert.Equal(t, tt.expected, z.Tiers(tt.boundaries, tt.reverse))
+		})
+	}
+}
+
+func TestZSet_RankOfScore(t *testing.T) {
+	setup := func() *ZSet[string] {
+		z := NewZSet()
+		z.Add("a", 10)
+		z.Add("b", 20)
+		z.Add("c", 20)
+		z.Add("d", 30)
+		return z
+	}
+
+	tests := []struct {
+		name     string
+		setup    func() *ZSet[string]
+		score    float64
+		mode     RankMode
+		reverse  bool
+		expected int64
+	}{
+		{name: "empty set floor", setup: NewZSet, score: 10, mode: RankFloor, expected: -1},
+		{name: "empty set ceil", setup: NewZSet, score: 10, mode: RankCeil, expected: -1},
+		{name: "floor between members", setup: setup, score: 25, mode: RankFloor, expected: 2},
+		{name: "ceil between members", setup: setup, score: 25, mode: RankCeil, expected: 3},
+		{name: "floor on duplicates", setup: setup, score: 20, mode: RankFloor, expected: 2},
+		{name: "ceil on duplicates", setup: setup, score: 20, mode: RankCeil, expected: 1},
+		{name: "floor below all", setup: setup, score: 5, mode: RankFloor, expected: -1},
+		{name: "ceil below all", setup: setup, score: 5, mode: RankCeil, expected: 0},
+		{name: "floor above all", setup: setup, score: 35, mode: RankFloor, expected: 3},
+		{name: "ceil above all", setup: setup, score: 35, mode: RankCeil, expected: -1},
+		{name: "floor reverse", setup: setup, score: 25, mode: RankFloor, reverse: true, expected: 1},
+		{name: "ceil reverse", setup: setup, score: 25, mode: RankCeil, reverse: true, expected: 0},
+		{name: "floor nan", setup: setup, score: math.NaN(), mode: RankFloor, expected: -1},
+		{name: "ceil nan", setup: setup, score: math.NaN(), mode: RankCeil, expected: -1},
+		{name: "ceil nan reverse", setup: setup, score: math.NaN(), mode: RankCeil, reverse: true, expected: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := tt.setup()
+			assert.Equal(t, tt.expected, z.RankOfScore(tt.score, tt.mode, tt.reverse))
+		})
+	}
+}
+
+func TestZSet_CountStrictlyBetween(t *testing.T) {
+	setup := func() *ZSet[string] {
+		z := NewZSet()
+		z.Add("a", 1)
+		z.Add("b", 2)
+		z.Add("c", 2)
+		z.Add("d", 3)
+		z.Add("e", 4)
+		z.Add("f", 4)
+		z.Add("g", 5)
+		return z
+	}
+
+	tests := []struct {
+		name     string
+		setup    func() *ZSet[string]
+		min      float64
+		max      float64
+		expected uint64
+	}{
+		{name: "empty set", setup: NewZSet, min: 0, max: 10, expected: 0},
+		{name: "members on both bounds excluded", setup: setup, min: 2, max: 4, expected: 1},
+		{name: "bounds between members", setup: setup, min: 1.5, max: 4.5, expected: 5},
+		{name: "whole range", setup: setup, min: 0, max: 10, expected: 7},
+		{name: "min equals max", setup: setup, min: 2, max: 2, expected: 0},
+		{name: "inverted range", setup: setup, min: 4, max: 2, expected: 0},
+		{name: "nan bound", setup: setup, min: math.NaN(), max: 10, expected: 0},
+		{name: "adjacent bounds", setup: setup, min: 3, max: 4, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := tt.setup()
+			assert.Equal(t, tt.expected, z.CountStrictlyBetween(tt.min, tt.max))
+		})
+	}
+}
+
+func TestZSet_RangeAfterToken(t *testing.T) {
+	type entry = struct {
+		Member string
+		Score  float64
+	}
+
+	setup := func() *ZSet[string] {
+		z := NewZSet()
+		z.Add("a", 1)
+		z.Add("b", 2)
+		z.Add("c", 2)
+		z.Add("d", 3)
+		z.Add("e", 4)
+		return z
+	}
+
+	t.Run("paginate whole set", func(t *testing.T) {
+		z := setup()
+		var all []entry
+		token := ""
+		for {
+			page, err := z.RangeAfterToken(token, 2)
+			assert.NoError(t, err)
+			if len(page) == 0 {
+				break
+			}
+			all = append(all, page...)
+			last := page[len(page)-1]
+			token, err = z.PageToken(last.Member, last.Score)
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, []entry{{"a", 1}, {"b", 2}, {"c", 2}, {"d", 3}, {"e", 4}}, all)
+	})
+
+	t.Run("resume within duplicate scores", func(t *testing.T) {
+		z := setup()
+		token, err := z.PageToken("b", 2)
+		assert.NoError(t, err)
+		page, err := z.RangeAfterToken(token, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, []entry{{"c", 2}, {"d", 3}, {"e", 4}}, page)
+	})
+
+	t.Run("stable under concurrent inserts", func(t *testing.T) {
+		z := setup()
+		token, err := z.PageToken("c", 2)
+		assert.NoError(t, err)
+		z.Add("aa", 0.5)
+		z.Add("bb", 2)
+		page, err := z.RangeAfterToken(token, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []entry{{"d", 3}, {"e", 4}}, page)
+	})
+
+	t.Run("token after removed member", func(t *testing.T) {
+		z := setup()
+		token, err := z.PageToken("c", 2)
+		assert.NoError(t, err)
+		z.Remove("c")
+		page, err := z.RangeAfterToken(token, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []entry{{"d", 3}}, page)
+	})
+
+	t.Run("token past the end", func(t *testing.T) {
+		z := setup()
+		token, err := z.PageToken("e", 4)
+		assert.NoError(t, err)
+		page, err := z.RangeAfterToken(token, -1)
+		assert.NoError(t, err)
+		assert.Nil(t, page)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		z := setup()
+		_, err := z.RangeAfterToken("!!!", 1)
+		assert.ErrorIs(t, err, ErrInvalidPageToken)
+
+		_, err = z.RangeAfterToken("AAAA", 1)
+		assert.ErrorIs(t, err, ErrInvalidPageToken)
+	})
+}
+
+func TestZSet_TopNWeightedAverage(t *testing.T) {
+	setup := func() *ZSet[string] {
+		z := NewZSet()
+		z.Add("a", 10)
+		z.Add("b", 20)
+		z.Add("c", 30)
+		z.Add("d", 40)
+		return z
+	}
+	uniform := func(int64) float64 { return 1 }
+	linear := func(rank int64) float64 { return float64(3 - rank) }
+
+	tests := []struct {
+		name     string
+		setup    func() *ZSet[string]
+		n        int64
+		weight   func(int64) float64
+		expected float64
+		ok       bool
+	}{
+		{name: "empty set", setup: NewZSet, n: 3, weight: uniform, ok: false},
+		{name: "zero n", setup: setup, n: 0, weight: uniform, ok: false},
+		{name: "uniform weights", setup: setup, n: 2, weight: uniform, expected: 35, ok: true},
+		{name: "rank weighted", setup: setup, n: 3, weight: linear, expected: (3*40 + 2*30 + 1*20) / 6.0, ok: true},
+		{name: "n larger than set", setup: setup, n: 10, weight: uniform, expected: 25, ok: true},
+		{name: "zero weights", setup: setup, n: 2, weight: func(int64) float64 { return 0 }, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := tt.setup()
+			avg, ok := z.TopNWeightedAverage(tt.n, tt.weight)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.InDelta(t, tt.expected, avg, 1e-9)
+			}
+		})
+	}
+}
+
+func TestZSet_MergeCaseInsensitive(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func() *ZSet[string]
+		wantMerged int
+		wantDict   map[string]float64
+		wantOrder  []string
+	}{
+		{
+			name:       "empty set",
+			setup:      NewZSet,
+			wantMerged: 0,
+			wantDict:   map[string]float64{},
+		},
+		{
+			name: "already normalized",
+			setup: func() *ZSet[string] {
+				z := NewZSet()
+				z.Add("alice", 1)
+				z.Add("bob", 2)
+				return z
+			},
+			wantMerged: 0,
+			wantDict:   map[string]float64{"alice": 1, "bob": 2},
+			wantOrder:  []string{"alice", "bob"},
+		},
+		{
+			name: "duplicates keep max score",
+			setup: func() *ZSet[string] {
+				z := NewZSet()
+				z.Add("Alice", 5)
+				z.Add("alice", 3)
+				z.Add("ALICE", 4)
+				z.Add("bob", 2)
+				return z
+			},
+			wantMerged: 2,
+			wantDict:   map[string]float64{"alice": 5, "bob": 2},
+			wantOrder:  []string{"bob", "alice"},
+		},
+		{
+			name: "single mixed case member is lowered",
+			setup: func() *ZSet[string] {
+				z := NewZSet()
+				z.Add("Carol", 1)
+				z.Add("dave", 2)
+				return z
+			},
+			wantMerged: 0,
+			wantDict:   map[string]float64{"carol": 1, "dave": 2},
+			wantOrder:  []string{"carol", "dave"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := tt.setup()
+			assert.Equal(t, tt.wantMerged, z.MergeCaseInsensitive())
+			assert.Equal(t, tt.wantDict, z.dict)
+			assert.Equal(t, uint64(len(tt.wantDict)), z.Len())
+			for i, member := range tt.wantOrder {
+				assert.Equal(t, int64(i), z.Rank(member, false))
+			}
+		})
+	}
+}
+
+func TestZSet_MemberFootprint(t *testing.T) {
+	t.Run("missing member", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("a", 1)
+		size, ok := z.MemberFootprint("b")
+		assert.False(t, ok)
+		assert.Equal(t, uint64(0), size)
+	})
+
+	t.Run("accounts for level count and string length", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("short", 1)
+		z.Add("a much longer member name", 2)
+
+		for _, ele := range []string{"short", "a much longer member name"} {
+			score, _ := z.Score(ele)
+			node := z.zsl.getNode(score, ele)
+			assert.NotNil(t, node)
+
+			expected := uint64(unsafe.Sizeof(skiplistNode[string]{})) +
+				uint64(len(node.level))*uint64(unsafe.Sizeof(skiplistLevel[string]{})) +
+				uint64(len(ele)) +
+				uint64(unsafe.Sizeof(ele)) + uint64(unsafe.Sizeof(score))
+
+			size, ok := z.MemberFootprint(ele)
+			assert.True(t, ok)
+			assert.Equal(t, expected, size)
+		}
+	})
+
+	t.Run("payload entry counted only when set", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("a", 1)
+		before, _ := z.MemberFootprint("a")
+
+		z.AddP("a", 1, 42)
+		after, _ := z.MemberFootprint("a")
+		var payload any
+		assert.Equal(t, before+uint64(unsafe.Sizeof("a"))+uint64(unsafe.Sizeof(payload)), after)
+
+		z.AddP("a", 1, nil)
+		cleared, _ := z.MemberFootprint("a")
+		assert.Equal(t, before, cleared)
+	})
+}
+
+func TestZSet_RebuildWithMaxLevel(t *testing.T) {
+	t.Run("invalid max level", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("a", 1)
+		assert.ErrorIs(t, z.RebuildWithMaxLevel(0), ErrInvalidMaxLevel)
+		assert.ErrorIs(t, z.RebuildWithMaxLevel(65), ErrInvalidMaxLevel)
+		assert.Equal(t, SKIPLIST_MAXLEVEL, z.zsl.maxLevel)
+	})
+
+	for _, maxLevel := range []int{1, 2, 8, 64} {
+		t.Run("rebuild preserves order at max level "+strconv.Itoa(maxLevel), func(t *testing.T) {
+			z := NewZSet()
+			for i := 0; i < 500; i++ {
+				z.Add("member"+strconv.Itoa(i), float64(i%37))
+			}
+			before := z.RangeByScore(0, 100, 0, -1)
+
+			assert.NoError(t, z.RebuildWithMaxLevel(maxLevel))
+			assert.Equal(t, maxLevel, z.zsl.maxLevel)
+			assert.Len(t, z.zsl.header.level, maxLevel)
+			assert.LessOrEqual(t, z.zsl.level, maxLevel)
+			for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+				assert.LessOrEqual(t, len(x.level), maxLevel)
+			}
+			assert.Equal(t, before, z.RangeByScore(0, 100, 0, -1))
+
+			// 重建后的插入和排名仍然正确
+			z.Add("zzz", 100)
+			assert.Equal(t, int64(0), z.Rank("zzz", true))
+			for i, entry := range before {
+				assert.Equal(t, int64(i), z.Rank(entry.Member, false))
+			}
+		})
+	}
+}
+
+func TestZSet_BucketSums(t *testing.T) {
+	type bucket = struct {
+		Count uint64
+		Sum   float64
+	}
+
+	setup := func() *ZSet[string] {
+		z := NewZSet()
+		z.Add("a", -1)
+		z.Add("b", 0)
+		z.Add("c", 2)
+		z.Add("d", 5)
+		z.Add("e", 7)
+		z.Add("f", 10)
+		z.Add("g", 11)
+		return z
+	}
+	withInf := func() *ZSet[string] {
+		z := setup()
+		z.Add("-inf", math.Inf(-1))
+		z.Add("+inf", math.Inf(1))
+		z.Add("min", -math.MaxFloat64)
+		z.Add("max", math.MaxFloat64)
+		return z
+	}
+
+	tests := []struct {
+		name     string
+		setup    func() *ZSet[string]
+		min      float64
+		max      float64
+		bins     int
+		expected []bucket
+	}{
+		{name: "empty set", setup: NewZSet, min: 0, max: 10, bins: 2, expected: []bucket{{}, {}}},
+		{name: "zero bins", setup: setup, min: 0, max: 10, bins: 0, expected: nil},
+		{name: "max not greater than min", setup: setup, min: 10, max: 10, bins: 2, expected: nil},
+		{name: "infinite max", setup: withInf, min: 0, max: math.Inf(1), bins: 4, expected: nil},
+		{name: "infinite min", setup: withInf, min: math.Inf(-1), max: 10, bins: 4, expected: nil},
+		{name: "nan min", setup: setup, min: math.NaN(), max: 10, bins: 2, expected: nil},
+		{name: "nan max", setup: setup, min: 0, max: math.NaN(), bins: 2, expected: nil},
+		{name: "width overflows", setup: withInf, min: -math.MaxFloat64, max: math.MaxFloat64, bins: 2, expected: nil},
+		{
+			name:     "two bins with max in last bin",
+			setup:    setup,
+			min:      0,
+			max:      10,
+			bins:     2,
+			expected: []bucket{{Count: 2, Sum: 2}, {Count: 3, Sum: 22}},
+		},
+		{
+			name:     "single bin",
+			setup:    setup,
+			min:      0,
+			max:      10,
+			bins:     1,
+			expected: []bucket{{Count: 5, Sum: 24}},
+		},
+		{
+			name:     "empty bins in the middle",
+			setup:    setup,
+			min:      0,
+			max:      10,
+			bins:     5,
+			expected: []bucket{{Count: 1, Sum: 0}, {Count: 1, Sum: 2}, {Count: 1, Sum: 5}, {Count: 1, Sum: 7}, {Count: 1, Sum: 10}},
+		},
+		{
+			name:     "finite range ignores infinite members",
+			setup:    withInf,
+			min:      0,
+			max:      10,
+			bins:     2,
+			expected: []bucket{{Count: 2, Sum: 2}, {Count: 3, Sum: 22}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := tt.setup()
+			assert.Equal(t, tt.expected, z.BucketSums(tt.min, tt.max, tt.bins))
+		})
+	}
+}
+
+func TestZSet_Histogram(t *testing.T) {
+	z := NewZSet()
+	z.Add("a", -1)
+	z.Add("b", 0)
+	z.Add("c", 2.5)
+	z.Add("d", 5)
+	z.Add("e", 7)
+	z.Add("f", 10)
+	z.Add("g", 11)
+
+	tests := []struct {
+		name     string
+		set      *ZSet[string]
+		edges    []float64
+		expected []int64
+	}{
+		{"evenly spaced", z, []float64{0, 2.5, 5, 7.5, 10}, []int64{1, 1, 2, 0}},
+		{"members on boundaries", z, []float64{-1, 5, 10, 11}, []int64{3, 2, 1}},
+		{"out of range members ignored", z, []float64{1, 8}, []int64{3}},
+		{"all below", z, []float64{20, 30}, []int64{0}},
+		{"all above", z, []float64{-10, -5}, []int64{0}},
+		{"repeated edge gives empty bucket", z, []float64{0, 5, 5, 12}, []int64{2, 0, 4}},
+		{"infinite edges", z, []float64{math.Inf(-1), 0, math.Inf(1)}, []int64{1, 6}},
+		{"empty set", NewZSet(), []float64{0, 1}, []int64{0}},
+		{"unsorted", z, []float64{0, 5, 3}, nil},
+		{"nan edge", z, []float64{0, math.NaN()}, nil},
+		{"single edge", z, []float64{0}, nil},
+		{"no edges", z, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.set.Histogram(tt.edges))
+		})
+	}
+}
+
+func TestZSet_IncrBy(t *testing.T) {
+	t.Run("increment existing member", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("a", 1)
+		assert.Equal(t, 3.5, z.IncrBy("a", 2.5))
+		score, exists := z.Score("a")
+		assert.True(t, exists)
+		assert.Equal(t, 3.5, score)
+		assert.Equal(t, uint64(1), z.Len())
+	})
+
+	t.Run("create missing member", func(t *testing.T) {
+		z := NewZSet()
+		assert.Equal(t, 5.0, z.IncrBy("a", 5))
+		score, exists := z.Score("a")
+		assert.True(t, exists)
+		assert.Equal(t, 5.0, score)
+		assert.Equal(t, uint64(1), z.Len())
+	})
+
+	t.Run("negative delta", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("a", 1)
+		assert.Equal(t, -2.0, z.IncrBy("a", -3))
+		assert.Equal(t, -2.0, z.IncrBy("b", -2))
+		assert.Equal(t, uint64(2), z.Len())
+	})
+
+	t.Run("zero delta keeps member", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("a", 1)
+		assert.Equal(t, 1.0, z.IncrBy("a", 0))
+		assert.Equal(t, uint64(1), z.Len())
+	})
+
+	t.Run("rank changes after increment", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("a", 1)
+		z.Add("b", 2)
+		z.Add("c", 3)
+		assert.Equal(t, int64(0), z.Rank("a", false))
+
+		z.IncrBy("a", 10)
+		assert.Equal(t, int64(2), z.Rank("a", false))
+		assert.Equal(t, int64(0), z.Rank("b", false))
+		assert.Equal(t, int64(0), z.Rank("a", true))
+
+		member, score, ok := z.GetByRank(2, false)
+		assert.True(t, ok)
+		assert.Equal(t, "a", member)
+		assert.Equal(t, 11.0, score)
+	})
+}
+
+func TestZSet_RemoveRangeByRank(t *testing.T) {
+	setup := func() *ZSet[string] {
+		z := NewZSet()
+		z.Add("a", 1)
+		z.Add("b", 2)
+		z.Add("c", 3)
+		z.Add("d", 4)
+		z.Add("e", 5)
+		return z
+	}
+
+	tests := []struct {
+		name     string
+		setup    func() *ZSet[string]
+		start    int64
+		stop     int64
+		want     int
+		wantDict map[string]float64
+	}{
+		{name: "empty set", setup: NewZSet, start: 0, stop: -1, want: 0, wantDict: map[string]float64{}},
+		{name: "middle range", setup: setup, start: 1, stop: 3, want: 3, wantDict: map[string]float64{"a": 1, "e": 5}},
+		{name: "whole set", setup: setup, start: 0, stop: -1, want: 5, wantDict: map[string]float64{}},
+		{name: "negative indices", setup: setup, start: -2, stop: -1, want: 2, wantDict: map[string]float64{"a": 1, "b": 2, "c": 3}},
+		{name: "keep top three", setup: setup, start: 0, stop: -4, want: 2, wantDict: map[string]float64{"c": 3, "d": 4, "e": 5}},
+		{name: "stop beyond length", setup: setup, start: 3, stop: 100, want: 2, wantDict: map[string]float64{"a": 1, "b": 2, "c": 3}},
+		{name: "start beyond length", setup: setup, start: 5, stop: 10, want: 0, wantDict: map[string]float64{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5}},
+		{name: "start after stop", setup: setup, start: 3, stop: 1, want: 0, wantDict: map[string]float64{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5}},
+		{name: "negative start clamped", setup: setup, start: -100, stop: 0, want: 1, wantDict: map[string]float64{"b": 2, "c": 3, "d": 4, "e": 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := tt.setup()
+			assert.Equal(t, tt.want, z.RemoveRangeByRank(tt.start, tt.stop))
+			assert.Equal(t, tt.wantDict, z.dict)
+			assert.Equal(t, uint64(len(tt.wantDict)), z.Len())
+
+			// 剩余元素的排名仍然连续
+			for i := int64(0); i < int64(z.Len()); i++ {
+				member, _, ok := z.GetByRank(i, false)
+				assert.True(t, ok)
+				assert.Equal(t, i, z.Rank(member, false))
+			}
+		})
+	}
+
+	t.Run("large set keeps spans consistent", func(t *testing.T) {
+		z := NewZSet()
+		for i := 0; i < 1000; i++ {
+			z.Add("member"+strconv.Itoa(i), float64(i))
+		}
+		assert.Equal(t, 500, z.RemoveRangeByRank(250, 749))
+		assert.Equal(t, uint64(500), z.Len())
+		for i := int64(0); i < int64(z.Len()); i++ {
+			member, _, ok := z.GetByRank(i, false)
+			assert.True(t, ok)
+			assert.Equal(t, i, z.Rank(member, false))
+		}
+	})
+}
+
+func TestZSet_TrimToSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxLen      uint64
+		keepHighest bool
+		removed     int
+		survivors   []string
+	}{
+		{"keep highest", 2, true, 3, []string{"d", "e"}},
+		{"keep lowest", 2, false, 3, []string{"a", "b"}},
+		{"already under cap", 10, true, 0, []string{"a", "b", "c", "d", "e"}},
+		{"exactly at cap", 5, false, 0, []string{"a", "b", "c", "d", "e"}},
+		{"zero keeps nothing", 0, true, 5, nil},
+		{"zero from tail", 0, false, 5, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := NewZSet()
+			for i, m := range []string{"a", "b", "c", "d", "e"} {
+				z.Add(m, float64(i))
+			}
+
+			assert.Equal(t, tt.removed, z.TrimToSize(tt.maxLen, tt.keepHighest))
+			assert.Equal(t, uint64(len(tt.survivors)), z.Len())
+			if tt.survivors == nil {
+				assert.Empty(t, z.Members(false))
+			} else {
+				assert.Equal(t, tt.survivors, z.Members(false))
+			}
+			assert.NoError(t, z.Validate())
+		})
+	}
+
+	assert.Equal(t, 0, NewZSet().TrimToSize(0, true))
+}
+
+func TestZSet_RemoveRangeByScore(t *testing.T) {
+	setup := func() *ZSet[string] {
+		z := NewZSet()
+		z.Add("a", 1)
+		z.Add("b", 2)
+		z.Add("c", 2)
+		z.Add("d", 2)
+		z.Add("e", 3)
+		z.Add("f", 4)
+		return z
+	}
+
+	tests := []struct {
+		name     string
+		setup    func() *ZSet[string]
+		min      float64
+		max      float64
+		want     int
+		wantDict map[string]float64
+	}{
+		{name: "empty set", setup: NewZSet, min: 0, max: 10, want: 0, wantDict: map[string]float64{}},
+		{name: "range over duplicates", setup: setup, min: 2, max: 2, want: 3, wantDict: map[string]float64{"a": 1, "e": 3, "f": 4}},
+		{name: "range spanning duplicates", setup: setup, min: 1.5, max: 3, want: 4, wantDict: map[string]float64{"a": 1, "f": 4}},
+		{name: "expire below cutoff", setup: setup, min: -100, max: 2, want: 4, wantDict: map[string]float64{"e": 3, "f": 4}},
+		{name: "no match", setup: setup, min: 5, max: 10, want: 0, wantDict: map[string]float64{"a": 1, "b": 2, "c": 2, "d": 2, "e": 3, "f": 4}},
+		{name: "gap between members", setup: setup, min: 3.1, max: 3.9, want: 0, wantDict: map[string]float64{"a": 1, "b": 2, "c": 2, "d": 2, "e": 3, "f": 4}},
+		{name: "inverted range", setup: setup, min: 4, max: 1, want: 0, wantDict: map[string]float64{"a": 1, "b": 2, "c": 2, "d": 2, "e": 3, "f": 4}},
+		{name: "whole set", setup: setup, min: 1, max: 4, want: 6, wantDict: map[string]float64{}},
+		{name: "nan min", setup: setup, min: math.NaN(), max: 2, want: 0, wantDict: map[string]float64{"a": 1, "b": 2, "c": 2, "d": 2, "e": 3, "f": 4}},
+		{name: "nan max", setup: setup, min: 2, max: math.NaN(), want: 0, wantDict: map[string]float64{"a": 1, "b": 2, "c": 2, "d": 2, "e": 3, "f": 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := tt.setup()
+			assert.Equal(t, tt.want, z.RemoveRangeByScore(tt.min, tt.max))
+			assert.Equal(t, tt.wantDict, z.dict)
+			assert.Equal(t, uint64(len(tt.wantDict)), z.Len())
+
+			for i := int64(0); i < int64(z.Len()); i++ {
+				member, _, ok := z.GetByRank(i, false)
+				assert.True(t, ok)
+				assert.Equal(t, i, z.Rank(member, false))
+			}
+		})
+	}
+}
+
+func TestZSet_Count(t *testing.T) {
+	setup := func() *ZSet[string] {
+		z := NewZSet()
+		z.Add("a", 1)
+		z.Add("b", 2)
+		z.Add("c", 2)
+		z.Add("d", 3)
+		z.Add("e", 4)
+		z.Add("f", 4)
+		return z
+	}
+
+	tests := []struct {
+		name     string
+		setup    func() *ZSet[string]
+		min      float64
+		max      float64
+		expected int64
+	}{
+		{name: "empty set", setup: NewZSet, min: 0, max: 10, expected: 0},
+		{name: "whole set", setup: setup, min: 1, max: 4, expected: 6},
+		{name: "duplicates on both boundaries", setup: setup, min: 2, max: 4, expected: 5},
+		{name: "min equals max on duplicates", setup: setup, min: 2, max: 2, expected: 2},
+		{name: "between members", setup: setup, min: 2.5, max: 3.5, expected: 1},
+		{name: "no match", setup: setup, min: 5, max: 6, expected: 0},
+		{name: "min greater than max", setup: setup, min: 4, max: 1, expected: 0},
+		{name: "nan min", setup: setup, min: math.NaN(), max: 2, expected: 0},
+		{name: "nan max", setup: setup, min: 2, max: math.NaN(), expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := tt.setup()
+			assert.Equal(t, tt.expected, z.Count(tt.min, tt.max))
+			assert.Equal(t, int(tt.expected), len(z.RangeByScore(tt.min, tt.max, 0, -1)))
+		})
+	}
+}
+
+// newBenchZSet 创建包含 n 个元素的集合，分数依次为 0..n-1，供基准测试使用。
+func newBenchZSet(n int) *ZSet[string] {
+	z := NewZSet()
+	for i := 0; i < n; i++ {
+		z.Add("member"+strconv.Itoa(i), float64(i))
+	}
+	return z
+}
+
+func BenchmarkZSet_Count(b *testing.B) {
+	z := newBenchZSet(100000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.Count(1000, 90000)
+	}
+}
+
+func BenchmarkZSet_CountViaRangeByScore(b *testing.B) {
+	z := newBenchZSet(100000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = len(z.RangeByScore(1000, 90000, 0, -1))
+	}
+}
+
+func TestZSet_AppendRangeByScore(t *testing.T) {
+	type entry = struct {
+		Member string
+		Score  float64
+	}
+
+	z := NewZSet()
+	for i := 0; i < 10; i++ {
+		z.Add("m"+strconv.Itoa(i), float64(i))
+	}
+
+	tests := []struct {
+		name          string
+		min, max      float64
+		offset, count int64
+	}{
+		{"all", math.Inf(-1), math.Inf(1), 0, -1},
+		{"inner window", 2, 6, 0, -1},
+		{"offset and count", 2, 6, 1, 2},
+		{"offset past end", 2, 6, 10, -1},
+		{"zero count", 2, 6, 0, 0},
+		{"inverted", 6, 2, 0, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := z.RangeByScore(tt.min, tt.max, tt.offset, tt.count)
+
+			// 追加到 nil 与 RangeByScore 完全一致
+			assert.Equal(t, expected, z.AppendRangeByScore(nil, tt.min, tt.max, tt.offset, tt.count))
+
+			// 追加到已有内容之后，保留原有元素
+			prefix := []entry{{Member: "prefix", Score: -1}}
+			got := z.AppendRangeByScore(prefix, tt.min, tt.max, tt.offset, tt.count)
+			assert.Equal(t, append([]entry{{Member: "prefix", Score: -1}}, expected...), got)
+		})
+	}
+
+	// 容量足够时复用底层数组
+	buf := make([]entry, 0, 16)
+	got := z.AppendRangeByScore(buf, 0, 9, 0, -1)
+	assert.Len(t, got, 10)
+	assert.Same(t, &buf[:1][0], &got[0])
+}
+
+func TestZSet_RangeByScoreMulti(t *testing.T) {
+	z := NewZSet()
+	for i := 0; i < 10; i++ {
+		z.Add("m"+strconv.Itoa(i), float64(i))
+	}
+
+	members := func(items []struct {
+		Member string
+		Score  float64
+	}) []string {
+		if items == nil {
+			return nil
+		}
+		result := make([]string, len(items))
+		for i, item := range items {
+			result[i] = item.Member
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		windows  [][2]float64
+		expected [][]string
+	}{
+		{"adjacent", [][2]float64{{0, 2}, {3, 5}, {6, 9}}, [][]string{{"m0", "m1", "m2"}, {"m3", "m4", "m5"}, {"m6", "m7", "m8", "m9"}}},
+		{"shared boundary", [][2]float64{{0, 2}, {2, 4}}, [][]string{{"m0", "m1", "m2"}, {"m2", "m3", "m4"}}},
+		{"overlapping", [][2]float64{{1, 5}, {3, 4}}, [][]string{{"m1", "m2", "m3", "m4", "m5"}, {"m3", "m4"}}},
+		{"out of order", [][2]float64{{8, 9}, {0, 1}}, [][]string{{"m8", "m9"}, {"m0", "m1"}}},
+		{"matches nothing", [][2]float64{{2.2, 2.8}, {20, 30}, {-5, -1}}, [][]string{nil, nil, nil}},
+		{"single point", [][2]float64{{4, 4}}, [][]string{{"m4"}}},
+		{"no windows", nil, [][]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := z.RangeByScoreMulti(tt.windows)
+			assert.Len(t, result, len(tt.windows))
+			for i, items := range result {
+				assert.Equal(t, tt.expected[i], members(items))
+				assert.Equal(t, z.RangeByScore(tt.windows[i][0], tt.windows[i][1], 0, -1), items)
+			}
+		})
+	}
+
+	t.Run("invalid windows", func(t *testing.T) {
+		assert.Nil(t, z.RangeByScoreMulti([][2]float64{{0, 2}, {5, 3}}))
+		assert.Nil(t, z.RangeByScoreMulti([][2]float64{{math.NaN(), 2}}))
+	})
+}
+
+func BenchmarkZSet_AppendRangeByScore(b *testing.B) {
+	z := newBenchZSet(100000)
+	b.Run("fresh", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = z.RangeByScore(1000, 1999, 0, -1)
+		}
+	})
+	b.Run("reuse", func(b *testing.B) {
+		var buf []struct {
+			Member string
+			Score  float64
+		}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf = z.AppendRangeByScore(buf[:0], 1000, 1999, 0, -1)
+		}
+	})
+}
+
+func TestZSet_RangeByScoreBounds(t *testing.T) {
+	type entry = struct {
+		Member string
+		Score  float64
+	}
+
+	setup := func() *ZSet[string] {
+		z := NewZSet()
+		z.Add("a", 1)
+		z.Add("b", 2)
+		z.Add("c", 2)
+		z.Add("d", 3)
+		z.Add("e", 4)
+		return z
+	}
+
+	tests := []struct {
+		name         string
+		min          float64
+		minExclusive bool
+		max          float64
+		maxExclusive bool
+		offset       int64
+		count        int64
+		expected     []entry
+	}{
+		{name: "both inclusive", min: 2, max: 3, count: -1, expected: []entry{{"b", 2}, {"c", 2}, {"d", 3}}},
+		{name: "min exclusive", min: 2, minExclusive: true, max: 3, count: -1, expected: []entry{{"d", 3}}},
+		{name: "max exclusive", min: 2, max: 3, maxExclusive: true, count: -1, expected: []entry{{"b", 2}, {"c", 2}}},
+		{name: "both exclusive", min: 1, minExclusive: true, max: 4, maxExclusive: true, count: -1, expected: []entry{{"b", 2}, {"c", 2}, {"d", 3}}},
+		{name: "both exclusive on same score", min: 2, minExclusive: true, max: 2, maxExclusive: true, count: -1, expected: nil},
+		{name: "exclusive with offset and count", min: 1, minExclusive: true, max: 4, offset: 1, count: 2, expected: []entry{{"c", 2}, {"d", 3}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := setup()
+			result := z.RangeByScoreBounds(tt.min, tt.minExclusive, tt.max, tt.maxExclusive, tt.offset, tt.count)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+
+	t.Run("inclusive matches RangeByScore", func(t *testing.T) {
+		z := setup()
+		assert.Equal(t, z.RangeByScore(1, 3, 1, 2), z.RangeByScoreBounds(1, false, 3, false, 1, 2))
+	})
+}
+
+func TestZSet_RangeByScoreInfinity(t *testing.T) {
+	type entry = struct {
+		Member string
+		Score  float64
+	}
+
+	inf := math.Inf(1)
+	negInf := math.Inf(-1)
+
+	setup := func() *ZSet[string] {
+		z := NewZSet()
+		z.Add("a", -1e300)
+		z.Add("b", 1)
+		z.Add("c", 2)
+		z.Add("d", math.MaxFloat64)
+		return z
+	}
+
+	tests := []struct {
+		name     string
+		setup    func() *ZSet[string]
+		min      float64
+		max      float64
+		expected []entry
+	}{
+		{name: "both unbounded", setup: setup, min: negInf, max: inf, expected: []entry{{"a", -1e300}, {"b", 1}, {"c", 2}, {"d", math.MaxFloat64}}},
+		{name: "unbounded max", setup: setup, min: 1.5, max: inf, expected: []entry{{"c", 2}, {"d", math.MaxFloat64}}},
+		{name: "unbounded min", setup: setup, min: negInf, max: 1, expected: []entry{{"a", -1e300}, {"b", 1}}},
+		{name: "empty set unbounded", setup: NewZSet, min: negInf, max: inf, expected: nil},
+		{
+			name: "members with infinite scores",
+			setup: func() *ZSet[string] {
+				z := NewZSet()
+				z.Add("low", negInf)
+				z.Add("mid", 0)
+				z.Add("high", inf)
+				return z
+			},
+			min:      negInf,
+			max:      inf,
+			expected: []entry{{"low", negInf}, {"mid", 0}, {"high", inf}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := tt.setup()
+			assert.Equal(t, tt.expected, z.RangeByScore(tt.min, tt.max, 0, -1))
+			assert.Equal(t, int64(len(tt.expected)), z.Count(tt.min, tt.max))
+		})
+	}
+
+	t.Run("exclusive infinite bounds", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("low", negInf)
+		z.Add("mid", 0)
+		z.Add("high", inf)
+		assert.Equal(t, []entry{{"mid", 0}}, z.RangeByScoreBounds(negInf, true, inf, true, 0, -1))
+		assert.Equal(t, []entry{{"mid", 0}, {"high", inf}}, z.RangeByScoreBounds(negInf, true, inf, false, 0, -1))
+	})
+}
+
+func TestZSet_RangeByScoreInverted(t *testing.T) {
+	z := NewZSet()
+	z.Add("a", 5)
+	z.Add("b", 7)
+	z.Add("c", 10)
+	z.Add("d", 12)
+
+	assert.Nil(t, z.RangeByScore(10, 5, 0, -1))
+	assert.Nil(t, z.RangeByScoreBounds(10, false, 5, false, 0, -1))
+	assert.Nil(t, z.RangeByScore(math.Inf(1), math.Inf(-1), 0, -1))
+}
+
+func TestZSet_PopMin(t *testing.T) {
+	t.Run("empty set", func(t *testing.T) {
+		z := NewZSet()
+		ele, score, ok := z.PopMin()
+		assert.False(t, ok)
+		assert.Equal(t, "", ele)
+		assert.Equal(t, 0.0, score)
+	})
+
+	t.Run("repeated pops in ascending order", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("c", 3)
+		z.Add("a", 1)
+		z.Add("b", 1)
+		z.Add("d", 4)
+
+		expected := []struct {
+			ele   string
+			score float64
+		}{{"a", 1}, {"b", 1}, {"c", 3}, {"d", 4}}
+		for i, want := range expected {
+			ele, score, ok := z.PopMin()
+			assert.True(t, ok)
+			assert.Equal(t, want.ele, ele)
+			assert.Equal(t, want.score, score)
+			assert.Equal(t, uint64(len(expected)-i-1), z.Len())
+			_, exists := z.Score(ele)
+			assert.False(t, exists)
+		}
+
+		_, _, ok := z.PopMin()
+		assert.False(t, ok)
+	})
+}
+
+func TestZSet_PopMax(t *testing.T) {
+	t.Run("empty set", func(t *testing.T) {
+		z := NewZSet()
+		ele, score, ok := z.PopMax()
+		assert.False(t, ok)
+		assert.Equal(t, "", ele)
+		assert.Equal(t, 0.0, score)
+	})
+
+	t.Run("repeated pops in descending order", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("c", 3)
+		z.Add("a", 1)
+		z.Add("b", 1)
+		z.Add("d", 4)
+
+		expected := []struct {
+			ele   string
+			score float64
+		}{{"d", 4}, {"c", 3}, {"b", 1}, {"a", 1}}
+		for i, want := range expected {
+			ele, score, ok := z.PopMax()
+			assert.True(t, ok)
+			assert.Equal(t, want.ele, ele)
+			assert.Equal(t, want.score, score)
+			assert.Equal(t, uint64(len(expected)-i-1), z.Len())
+		}
+
+		_, _, ok := z.PopMax()
+		assert.False(t, ok)
+	})
+}
+
+func TestZSet_PopMinIf(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxScore  float64
+		ele       string
+		score     float64
+		ok        bool
+		remaining uint64
+	}{
+		{"min below threshold", 5, "a", 2, true, 1},
+		{"min equal to threshold", 2, "a", 2, true, 1},
+		{"min above threshold", 1.5, "", 0, false, 2},
+		{"nan threshold", math.NaN(), "", 0, false, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := NewZSet()
+			z.Add("a", 2)
+			z.Add("b", 3)
+
+			ele, score, ok := z.PopMinIf(tt.maxScore)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.ele, ele)
+			assert.Equal(t, tt.score, score)
+			assert.Equal(t, tt.remaining, z.Len())
+			assert.NoError(t, z.Validate())
+		})
+	}
+
+	t.Run("empty set", func(t *testing.T) {
+		ele, score, ok := NewZSet().PopMinIf(math.Inf(1))
+		assert.False(t, ok)
+		assert.Equal(t, "", ele)
+		assert.Equal(t, 0.0, score)
+	})
+}
+
+func TestZSet_AddBatch(t *testing.T) {
+	t.Run("matches individual adds", func(t *testing.T) {
+		members := map[string]float64{"a": 3, "b": 1, "c": 2, "d": 2, "e": -1}
+
+		batch := NewZSet()
+		assert.Equal(t, len(members), batch.AddBatch(members))
+
+		single := NewZSet()
+		for ele, score := range members {
+			single.Add(ele, score)
+		}
+
+		assert.Equal(t, single.dict, batch.dict)
+		assert.Equal(t, single.Len(), batch.Len())
+		assert.Equal(t, single.RangeByScore(-10, 10, 0, -1), batch.RangeByScore(-10, 10, 0, -1))
+	})
+
+	t.Run("overlapping keys update scores", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("a", 1)
+		z.Add("b", 2)
+
+		added := z.AddBatch(map[string]float64{"a": 10, "b": 2, "c": 3})
+		assert.Equal(t, 1, added)
+		assert.Equal(t, uint64(3), z.Len())
+		assert.Equal(t, map[string]float64{"a": 10, "b": 2, "c": 3}, z.dict)
+		assert.Equal(t, int64(2), z.Rank("a", false))
+	})
+
+	t.Run("empty batch", func(t *testing.T) {
+		z := NewZSet()
+		assert.Equal(t, 0, z.AddBatch(nil))
+		assert.Equal(t, uint64(0), z.Len())
+	})
+}
+
+func TestZSet_IncrByBatch(t *testing.T) {
+	z := NewZSet()
+	z.Add("a", 10)
+	z.Add("b", 20)
+	z.Add("c", 30)
+	z.Add("inf", math.Inf(1))
+
+	z.IncrByBatch(map[string]float64{
+		"a":   25,           // 越过 b 和 c
+		"b":   -5,           // 仍在 a 之前
+		"new": 18,           // 新元素从 0 开始
+		"neg": -1,           // 新元素可以为负
+		"inf": math.Inf(-1), // 结果为 NaN，不做修改
+		"c":   0,            // 增量为 0 不改变位置
+	})
+
+	expected := []struct {
+		ele   string
+		score float64
+	}{{"neg", -1}, {"b", 15}, {"new", 18}, {"c", 30}, {"a", 35}, {"inf", math.Inf(1)}}
+	for rank, want := range expected {
+		score, ok := z.Score(want.ele)
+		assert.True(t, ok)
+		assert.Equal(t, want.score, score, want.ele)
+		assert.Equal(t, int64(rank), z.Rank(want.ele, false), want.ele)
+	}
+	assert.Equal(t, uint64(len(expected)), z.Len())
+	assert.NoError(t, z.Validate())
+
+	// 与逐个调用 IncrBy 的结果一致
+	deltas := map[string]float64{"a": 1, "x": 2, "b": -100}
+	expectedSet := z.Clone()
+	for ele, delta := range deltas {
+		expectedSet.IncrBy(ele, delta)
+	}
+	z.IncrByBatch(deltas)
+	assert.True(t, expectedSet.Equal(z))
+
+	z.IncrByBatch(nil)
+	assert.True(t, expectedSet.Equal(z))
+}
+
+func TestZSet_ReplaceAll(t *testing.T) {
+	z := NewZSetWithPayload()
+	z.AddP("old1", 1, "p")
+	z.Add("old2", 2)
+	z.Add("keep", 3)
+
+	z.ReplaceAll(map[string]float64{"keep": 30, "b": 20, "a": 20, "c": 5, "nan": math.NaN()})
+
+	assert.Equal(t, uint64(4), z.Len())
+	assert.False(t, z.Contains("old1"))
+	assert.False(t, z.Contains("old2"))
+	assert.False(t, z.Contains("nan"))
+	assert.Equal(t, []string{"c", "a", "b", "keep"}, z.Members(false))
+
+	tests := []struct {
+		ele   string
+		score float64
+		rank  int64
+	}{
+		{"c", 5, 0},
+		{"a", 20, 1},
+		{"b", 20, 2},
+		{"keep", 30, 3},
+	}
+	for _, tt := range tests {
+		score, ok := z.Score(tt.ele)
+		assert.True(t, ok)
+		assert.Equal(t, tt.score, score)
+		assert.Equal(t, tt.rank, z.Rank(tt.ele, false))
+	}
+
+	// 原有元素的载荷不会保留
+	payload, _ := z.Payload("keep")
+	assert.Nil(t, payload)
+	assert.NoError(t, z.Validate())
+
+	z.ReplaceAll(nil)
+	assert.Equal(t, uint64(0), z.Len())
+	assert.NoError(t, z.Validate())
+}
+
+func BenchmarkZSet_AddBatch(b *testing.B) {
+	members := make(map[string]float64, 10000)
+	for i := 0; i < 10000; i++ {
+		members["member"+strconv.Itoa(i)] = float64(i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z := NewZSet()
+		z.AddBatch(members)
+	}
+}
+
+func BenchmarkZSet_IncrByBatch(b *testing.B) {
+	z := newBenchZSet(100000)
+	deltas := make(map[string]float64, 1000)
+	for i := 0; i < 1000; i++ {
+		deltas["member"+strconv.Itoa(i*100)] = float64(i % 7)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.IncrByBatch(deltas)
+	}
+}
+
+func TestZSet_RangeByRank(t *testing.T) {
+	type entry = struct {
+		Member string
+		Score  float64
+	}
+
+	setup := func() *ZSet[string] {
+		z := NewZSet()
+		z.Add("a", 1)
+		z.Add("b", 2)
+		z.Add("c", 3)
+		z.Add("d", 4)
+		z.Add("e", 5)
+		return z
+	}
+
+	tests := []struct {
+		name     string
+		setup    func() *ZSet[string]
+		start    int64
+		stop     int64
+		reverse  bool
+		expected []entry
+	}{
+		{name: "empty set", setup: NewZSet, start: 0, stop: -1, expected: nil},
+		{name: "forward window", setup: setup, start: 1, stop: 3, expected: []entry{{"b", 2}, {"c", 3}, {"d", 4}}},
+		{name: "reverse window", setup: setup, start: 1, stop: 3, reverse: true, expected: []entry{{"d", 4}, {"c", 3}, {"b", 2}}},
+		{name: "whole set", setup: setup, start: 0, stop: -1, expected: []entry{{"a", 1}, {"b", 2}, {"c", 3}, {"d", 4}, {"e", 5}}},
+		{name: "negative indices", setup: setup, start: -3, stop: -2, expected: []entry{{"c", 3}, {"d", 4}}},
+		{name: "negative indices reverse", setup: setup, start: -2, stop: -1, reverse: true, expected: []entry{{"b", 2}, {"a", 1}}},
+		{name: "clamp stop", setup: setup, start: 3, stop: 100, expected: []entry{{"d", 4}, {"e", 5}}},
+		{name: "clamp start", setup: setup, start: -100, stop: 1, expected: []entry{{"a", 1}, {"b", 2}}},
+		{name: "start beyond length", setup: setup, start: 5, stop: 10, expected: nil},
+		{name: "start after stop", setup: setup, start: 3, stop: 1, expected: nil},
+		{name: "single element", setup: setup, start: 2, stop: 2, expected: []entry{{"c", 3}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := tt.setup()
+			assert.Equal(t, tt.expected, z.RangeByRank(tt.start, tt.stop, tt.reverse))
+		})
+	}
+}
+
+func TestNewZSetFunc(t *testing.T) {
+	t.Run("int members", func(t *testing.T) {
+		z := NewZSetFunc(func(a, b int64) bool { return a < b })
+		assert.True(t, z.Add(42, 10))
+		assert.True(t, z.Add(7, 10))
+		assert.True(t, z.Add(100, 5))
+		assert.False(t, z.Add(42, 20))
+
+		assert.Equal(t, uint64(3), z.Len())
+		assert.Equal(t, int64(0), z.Rank(100, false))
+		assert.Equal(t, int64(1), z.Rank(7, false))
+		assert.Equal(t, int64(0), z.Rank(42, true))
+
+		member, score, ok := z.GetByRank(1, false)
+		assert.True(t, ok)
+		assert.Equal(t, int64(7), member)
+		assert.Equal(t, 10.0, score)
+
+		assert.Equal(t, []struct {
+			Member int64
+			Score  float64
+		}{{100, 5}, {7, 10}}, z.RangeByScore(0, 10, 0, -1))
+
+		_, _, ok = NewZSetFunc(func(a, b int64) bool { return a < b }).GetByRank(0, false)
+		assert.False(t, ok)
+	})
+
+	t.Run("nil less", func(t *testing.T) {
+		z := NewZSetFunc[string](nil)
+		z.Add("b", 0)
+		z.Add("a", 0)
+		z.Add("c", 0)
+		assert.Equal(t, []string{"a", "b", "c"}, z.Members(false))
+		assert.NoError(t, z.Validate())
+
+		assert.Panics(t, func() { NewZSetFunc[int](nil) })
+	})
+
+	t.Run("tie-break follows comparator", func(t *testing.T) {
+		z := NewZSetFunc(func(a, b int) bool { return a > b })
+		z.Add(1, 0)
+		z.Add(2, 0)
+		z.Add(3, 0)
+		assert.Equal(t, int64(0), z.Rank(3, false))
+		assert.Equal(t, int64(2), z.Rank(1, false))
+		assert.True(t, z.Remove(2))
+		assert.Equal(t, int64(1), z.Rank(1, false))
+	})
+
+	t.Run("struct members", func(t *testing.T) {
+		type player struct {
+			Region string
+			ID     int
+		}
+		less := func(a, b player) bool {
+			if a.Region != b.Region {
+				return a.Region < b.Region
+			}
+			return a.ID < b.ID
+		}
+
+		z := NewZSetFunc(less)
+		z.Add(player{"eu", 2}, 50)
+		z.Add(player{"us", 1}, 50)
+		z.Add(player{"eu", 1}, 50)
+		z.Add(player{"ap", 9}, 70)
+
+		assert.Equal(t, int64(0), z.Rank(player{"eu", 1}, false))
+		assert.Equal(t, int64(1), z.Rank(player{"eu", 2}, false))
+		assert.Equal(t, int64(2), z.Rank(player{"us", 1}, false))
+		assert.Equal(t, int64(0), z.Rank(player{"ap", 9}, true))
+
+		score, ok := z.Score(player{"us", 1})
+		assert.True(t, ok)
+		assert.Equal(t, 50.0, score)
+
+		// 非字符串元素通过 JSON 编码分页令牌
+		token, err := z.PageToken(player{"eu", 2}, 50)
+		assert.NoError(t, err)
+		page, err := z.RangeAfterToken(token, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, []struct {
+			Member player
+			Score  float64
+		}{{player{"us", 1}, 50}, {player{"ap", 9}, 70}}, page)
+
+		// 大小写合并只作用于字符串元素
+		assert.Equal(t, 0, z.MergeCaseInsensitive())
+		assert.Equal(t, uint64(4), z.Len())
+	})
+}
+
+func TestZSet_ForEach(t *testing.T) {
+	setup := func() *ZSet[string] {
+		z := NewZSet()
+		z.Add("c", 3)
+		z.Add("a", 1)
+		z.Add("b", 2)
+		z.Add("d", 2)
+		return z
+	}
+
+	tests := []struct {
+		name     string
+		setup    func() *ZSet[string]
+		reverse  bool
+		limit    int
+		expected []string
+	}{
+		{name: "empty set", setup: NewZSet, limit: -1, expected: nil},
+		{name: "forward order", setup: setup, limit: -1, expected: []string{"a", "b", "d", "c"}},
+		{name: "reverse order", setup: setup, reverse: true, limit: -1, expected: []string{"c", "d", "b", "a"}},
+		{name: "early stop", setup: setup, limit: 2, expected: []string{"a", "b"}},
+		{name: "early stop reverse", setup: setup, reverse: true, limit: 1, expected: []string{"c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := tt.setup()
+			var visited []string
+			z.ForEach(tt.reverse, func(member string, score float64) bool {
+				s := z.dict[member]
+				assert.Equal(t, s, score)
+				visited = append(visited, member)
+				return tt.limit < 0 || len(visited) < tt.limit
+			})
+			assert.Equal(t, tt.expected, visited)
+		})
+	}
+}
+
+func TestZSet_All(t *testing.T) {
+	z := NewZSet()
+	z.Add("c", 3)
+	z.Add("a", 1)
+	z.Add("b", 2)
+
+	t.Run("ascending", func(t *testing.T) {
+		var members []string
+		var scores []float64
+		for member, score := range z.All() {
+			members = append(members, member)
+			scores = append(scores, score)
+		}
+		assert.Equal(t, []string{"a", "b", "c"}, members)
+		assert.Equal(t, []float64{1, 2, 3}, scores)
+	})
+
+	t.Run("descending", func(t *testing.T) {
+		var members []string
+		for member := range z.AllReverse() {
+			members = append(members, member)
+		}
+		assert.Equal(t, []string{"c", "b", "a"}, members)
+	})
+
+	t.Run("break early", func(t *testing.T) {
+		var members []string
+		for member := range z.All() {
+			members = append(members, member)
+			if member == "b" {
+				break
+			}
+		}
+		assert.Equal(t, []string{"a", "b"}, members)
+	})
+
+	t.Run("empty set", func(t *testing.T) {
+		for range NewZSet().All() {
+			t.Fatal("unexpected element")
+		}
+	})
+}
+
+func TestZSet_RandomMember(t *testing.T) {
+	z := NewZSet()
+	_, _, ok := z.RandomMember()
+	assert.False(t, ok)
+
+	z.Add("a", 1)
+	z.Add("b", 2)
+	z.Add("c", 3)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		member, score, ok := z.RandomMember()
+		assert.True(t, ok)
+		expected, _ := z.Score(member)
+		assert.Equal(t, expected, score)
+		seen[member] = true
+	}
+	assert.Len(t, seen, 3)
+}
+
+func TestZSet_WeightedSample(t *testing.T) {
+	t.Run("no positive weight", func(t *testing.T) {
+		z := NewZSet()
+		_, _, ok := z.WeightedSample()
+		assert.False(t, ok)
+
+		z.Add("zero", 0)
+		z.Add("negative", -5)
+		_, _, ok = z.WeightedSample()
+		assert.False(t, ok)
+
+		z.Add("max1", math.MaxFloat64)
+		z.Add("max2", math.MaxFloat64)
+		_, _, ok = z.WeightedSample()
+		assert.False(t, ok)
+	})
+
+	t.Run("distribution matches weights", func(t *testing.T) {
+		z := NewZSet()
+		z.Add("a", 1)
+		z.Add("b", 2)
+		z.Add("c", 3)
+		z.Add("d", 4)
+		z.Add("zero", 0)
+		z.Add("negative", -10)
+
+		const draws = 20000
+		counts := make(map[string]int)
+		for i := 0; i < draws; i++ {
+			member, score, ok := z.WeightedSample()
+			assert.True(t, ok)
+			expected, _ := z.Score(member)
+			assert.Equal(t, expected, score)
+			counts[member]++
+		}
+
+		assert.Zero(t, counts["zero"])
+		assert.Zero(t, counts["negative"])
+		for member, weight := range map[string]float64{"a": 1, "b": 2, "c": 3, "d": 4} {
+			assert.InDelta(t, weight/10, float64(counts[member])/draws, 0.02, "member %s", member)
+		}
+	})
+}
+
+func TestZSet_RandomMembers(t *testing.T) {
+	z := NewZSet()
+	assert.Empty(t, z.RandomMembers(3, true))
+	assert.Empty(t, z.RandomMembers(-3, true))
+
+	for i := 0; i < 10; i++ {
+		z.Add(strconv.Itoa(i), float64(i))
+	}
+
+	tests := []struct {
+		name     string
+		count    int64
+		expected int
+		distinct bool
+	}{
+		{"zero", 0, 0, true},
+		{"positive", 4, 4, true},
+		{"positive equals size", 10, 10, true},
+		{"positive capped at size", 20, 10, true},
+		{"negative allows repeats", -25, 25, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := z.RandomMembers(tt.count, true)
+			assert.Len(t, result, tt.expected)
+
+			seen := make(map[string]bool)
+			for i, r := range result {
+				score, ok := z.Score(r.Member)
+				assert.True(t, ok)
+				assert.Equal(t, score, r.Score)
+				if tt.distinct {
+					assert.False(t, seen[r.Member], "duplicate member %s", r.Member)
+					if i > 0 {
+						assert.Less(t, result[i-1].Score, r.Score)
+					}
+				}
+				seen[r.Member] = true
+			}
+		})
+	}
+
+	t.Run("without scores", func(t *testing.T) {
+		for _, r := range z.RandomMembers(-5, false) {
+			assert.Equal(t, float64(0), r.Score)
+		}
+	})
+}
+
+func TestZSet_Members(t *testing.T) {
+	z := NewZSet()
+	assert.Empty(t, z.Members(false))
+	assert.Empty(t, z.Members(true))
+
+	z.Add("b", 2)
+	z.Add("c", 3)
+	z.Add("a", 1)
+	z.Add("d", 2)
+
+	assert.Equal(t, []string{"a", "b", "d", "c"}, z.Members(false))
+	assert.Equal(t, []string{"c", "d", "b", "a"}, z.Members(true))
+}
+
+func TestZSet_Scores(t *testing.T) {
+	z := NewZSet()
+	assert.Empty(t, z.Scores(false))
+	assert.Empty(t, z.Scores(true))
+
+	z.Add("b", 2)
+	z.Add("c", 3.5)
+	z.Add("a", -1)
+	z.Add("d", 2)
+
+	assert.Equal(t, []float64{-1, 2, 2, 3.5}, z.Scores(false))
+	assert.Equal(t, []float64{3.5, 2, 2, -1}, z.Scores(true))
+	assert.Len(t, z.Scores(false), int(z.Len()))
+
+	// 与 Members 的顺序一一对应
+	members := z.Members(true)
+	for i, score := range z.Scores(true) {
+		expected, _ := z.Score(members[i])
+		assert.Equal(t, expected, score)
+	}
+}
+
+func TestZSet_MScore(t *testing.T) {
+	z := NewZSet()
+	z.Add("a", 1)
+	z.Add("b", 2)
+
+	result := z.MScore("b", "missing", "a", "b")
+	assert.Len(t, result, 4)
+	assert.Equal(t, float64(2), result[0].Score)
+	assert.True(t, result[0].Exists)
+	assert.Equal(t, float64(0), result[1].Score)
+	assert.False(t, result[1].Exists)
+	assert.Equal(t, float64(1), result[2].Score)
+	assert.True(t, result[2].Exists)
+	assert.Equal(t, float64(2), result[3].Score)
+	assert.True(t, result[3].Exists)
+
+	assert.Empty(t, z.MScore())
+}
+
+func TestZSet_ScoresOf(t *testing.T) {
+	z := NewZSet()
+	z.Add("a", 1)
+	z.Add("b", 2)
+	z.Add("c", 3)
+
+	tests := []struct {
+		name     string
+		members  []string
+		expected map[string]float64
+	}{
+		{"mixed present and absent", []string{"a", "missing", "c"}, map[string]float64{"a": 1, "c": 3}},
+		{"duplicates", []string{"b", "b"}, map[string]float64{"b": 2}},
+		{"all absent", []string{"x", "y"}, map[string]float64{}},
+		{"empty input", nil, map[string]float64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := z.ScoresOf(tt.members...)
+			assert.NotNil(t, result)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestZSet_AddOpt(t *testing.T) {
+	tests := []struct {
+		name          string
+		ele           string
+		score         float64
+		opts          AddOptions
+		expectAdded   bool
+		expectUpdated bool
+		expectChanged bool
+		expectScore   float64
+		expectExists  bool
+	}{
+		{"no flags adds", "new", 5, AddOptions{}, true, false, true, 5, true},
+		{"no flags updates", "a", 5, AddOptions{}, false, true, true, 5, true},
+		{"no flags same score", "a", 2, AddOptions{}, false, true, false, 2, true},
+		{"NX adds missing", "new", 5, AddOptions{NX: true}, true, false, true, 5, true},
+		{"NX skips existing", "a", 5, AddOptions{NX: true}, false, false, false, 2, true},
+		{"XX updates existing", "a", 5, AddOptions{XX: true}, false, true, true, 5, true},
+		{"XX same score", "a", 2, AddOptions{XX: true}, false, true, false, 2, true},
+		{"XX skips missing", "new", 5, AddOptions{XX: true}, false, false, false, 0, false},
+		{"GT updates on higher", "a", 3, AddOptions{GT: true}, false, true, true, 3, true},
+		{"GT skips lower", "a", 1, AddOptions{GT: true}, false, false, false, 2, true},
+		{"GT skips equal", "a", 2, AddOptions{GT: true}, false, false, false, 2, true},
+		{"GT adds missing", "new", 1, AddOptions{GT: true}, true, false, true, 1, true},
+		{"LT updates on lower", "a", 1, AddOptions{LT: true}, false, true, true, 1, true},
+		{"LT skips higher", "a", 3, AddOptions{LT: true}, false, false, false, 2, true},
+		{"LT skips equal", "a", 2, AddOptions{LT: true}, false, false, false, 2, true},
+		{"LT adds missing", "new", 1, AddOptions{LT: true}, true, false, true, 1, true},
+		{"XX GT updates on higher", "a", 3, AddOptions{XX: true, GT: true}, false, true, true, 3, true},
+		{"XX GT skips missing", "new", 3, AddOptions{XX: true, GT: true}, false, false, false, 0, false},
+		{"NX XX conflict", "new", 5, AddOptions{NX: true, XX: true}, false, false, false, 0, false},
+		{"NX GT conflict", "new", 5, AddOptions{NX: true, GT: true}, false, false, false, 0, false},
+		{"GT LT conflict", "a", 5, AddOptions{GT: true, LT: true}, false, false, false, 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := NewZSet()
+			z.Add("a", 2)
+			z.Add("b", 4)
+
+			added, updated, changed := z.AddOpt(tt.ele, tt.score, tt.opts)
+			assert.Equal(t, tt.expectAdded, added)
+			assert.Equal(t, tt.expectUpdated, updated)
+			assert.Equal(t, tt.expectChanged, changed)
+
+			score, exists := z.Score(tt.ele)
+			assert.Equal(t, tt.expectExists, exists)
+			assert.Equal(t, tt.expectScore, score)
+			if exists {
+				assert.Equal(t, tt.expectScore, z.RangeByScore(score, score, 0, -1)[0].Score)
+			}
+		})
+	}
+}
+
+func TestZSet_AddWithPrev(t *testing.T) {
+	z := NewZSet()
+
+	prev, existed := z.AddWithPrev("a", 1)
+	assert.False(t, existed)
+	assert.Equal(t, float64(0), prev)
+
+	prev, existed = z.AddWithPrev("a", 5)
+	assert.True(t, existed)
+	assert.Equal(t, float64(1), prev)
+
+	prev, existed = z.AddWithPrev("a", 5)
+	assert.True(t, existed)
+	assert.Equal(t, float64(5), prev)
+
+	score, _ := z.Score("a")
+	assert.Equal(t, float64(5), score)
+	assert.Equal(t, uint64(1), z.Len())
+}
+
+func TestZSet_Clear(t *testing.T) {
+	z := NewZSet()
+	for i := 0; i < 100; i++ {
+		z.Add(strconv.Itoa(i), float64(i))
+	}
+
+	z.Clear()
+	assert.Equal(t, uint64(0), z.Len())
+	assert.Empty(t, z.RangeByScore(math.Inf(-1), math.Inf(1), 0, -1))
+	_, exists := z.Score("1")
+	assert.False(t, exists)
+	_, _, ok := z.GetByRank(0, false)
+	assert.False(t, ok)
+
+	assert.True(t, z.Add("b", 2))
+	assert.True(t, z.Add("a", 1))
+	assert.True(t, z.Add("c", 3))
+	assert.Equal(t, uint64(3), z.Len())
+	assert.Equal(t, int64(0), z.Rank("a", false))
+	assert.Equal(t, int64(1), z.Rank("b", false))
+	assert.Equal(t, int64(2), z.Rank("c", false))
+	assert.Equal(t, int64(0), z.Rank("c", true))
+	member, _, _ := z.GetByRank(2, false)
+	assert.Equal(t, "c", member)
+
+	t.Run("zero value", func(t *testing.T) {
+		var z ZSet[string]
+		z.Clear()
+		assert.True(t, z.Add("a", 1))
+		assert.Equal(t, uint64(1), z.Len())
+	})
+}
+
+func TestZSet_Clone(t *testing.T) {
+	z := NewZSet()
+	z.Add("a", 1)
+	z.Add("b", 2)
+	z.Add("c", 3)
+
+	clone := z.Clone()
+	assert.Equal(t, z.RangeByRank(0, -1, false), clone.RangeByRank(0, -1, false))
+
+	clone.Add("a", 10)
+	clone.Remove("b")
+	clone.Add("d", 0)
+
+	assert.Equal(t, uint64(3), z.Len())
+	score, _ := z.Score("a")
+	assert.Equal(t, float64(1), score)
+	assert.Equal(t, int64(0), z.Rank("a", false))
+	assert.Equal(t, int64(1), z.Rank("b", false))
+	assert.Equal(t, int64(2), z.Rank("c", false))
+	_, exists := z.Score("d")
+	assert.False(t, exists)
+
+	assert.Equal(t, []string{"d", "c", "a"}, clone.Members(false))
+
+	t.Run("sync", func(t *testing.T) {
+		assert.Nil(t, z.Clone().mu)
+		assert.NotNil(t, NewSyncZSet().Clone().mu)
+	})
+}
+
+func TestZSet_CeilingFloor(t *testing.T) {
+	z := NewZSet()
+	_, _, ok := z.Ceiling(1)
+	assert.False(t, ok)
+	_, _, ok = z.Floor(1)
+	assert.False(t, ok)
+
+	z.Add("a", 10)
+	z.Add("b", 20)
+	z.Add("c", 20)
+	z.Add("d", 30)
+
+	tests := []struct {
+		name        string
+		score       float64
+		ceilMember  string
+		ceilScore   float64
+		ceilOK      bool
+		floorMember string
+		floorScore  float64
+		floorOK     bool
+	}{
+		{"below all", 5, "a", 10, true, "", 0, false},
+		{"exact match", 10, "a", 10, true, "a", 10, true},
+		{"between", 15, "b", 20, true, "a", 10, true},
+		{"exact tie", 20, "b", 20, true, "c", 20, true},
+		{"above all", 35, "", 0, false, "d", 30, true},
+		{"infinity", math.Inf(1), "", 0, false, "d", 30, true},
+		{"nan", math.NaN(), "", 0, false, "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			member, score, ok := z.Ceiling(tt.score)
+			assert.Equal(t, tt.ceilOK, ok)
+			assert.Equal(t, tt.ceilMember, member)
+			assert.Equal(t, tt.ceilScore, score)
+
+			member, score, ok = z.Floor(tt.score)
+			assert.Equal(t, tt.floorOK, ok)
+			assert.Equal(t, tt.floorMember, member)
+			assert.Equal(t, tt.floorScore, score)
+		})
+	}
+}
+
+func TestZSet_FirstLast(t *testing.T) {
+	z := NewZSet()
+	_, _, ok := z.First()
+	assert.False(t, ok)
+	_, _, ok = z.Last()
+	assert.False(t, ok)
+
+	z.Add("only", 5)
+	member, score, ok := z.First()
+	assert.True(t, ok)
+	assert.Equal(t, "only", member)
+	assert.Equal(t, float64(5), score)
+	member, score, ok = z.Last()
+	assert.True(t, ok)
+	assert.Equal(t, "only", member)
+	assert.Equal(t, float64(5), score)
+
+	z.Add("low", 1)
+	z.Add("high", 9)
+	z.Add("high2", 9)
+	member, score, _ = z.First()
+	assert.Equal(t, "low", member)
+	assert.Equal(t, float64(1), score)
+	member, score, _ = z.Last()
+	assert.Equal(t, "high2", member)
+	assert.Equal(t, float64(9), score)
+	assert.Equal(t, uint64(4), z.Len())
+}
+
+func TestZSet_MinMaxScore(t *testing.T) {
+	z := NewZSet()
+	_, ok := z.MinScore()
+	assert.False(t, ok)
+	_, ok = z.MaxScore()
+	assert.False(t, ok)
+
+	z.Add("a", -3)
+	minScore, ok := z.MinScore()
+	assert.True(t, ok)
+	maxScore, _ := z.MaxScore()
+	assert.Equal(t, float64(-3), minScore)
+	assert.Equal(t, minScore, maxScore)
+
+	z.Add("b", -7.5)
+	z.Add("c", 4)
+	minScore, _ = z.MinScore()
+	maxScore, _ = z.MaxScore()
+	assert.Equal(t, -7.5, minScore)
+	assert.Equal(t, float64(4), maxScore)
+
+	assert.Equal(t, float64(0), testing.AllocsPerRun(100, func() {
+		z.MinScore()
+		z.MaxScore()
+	}))
+}
+
+func TestZSet_RankWithScore(t *testing.T) {
+	z := NewZSet()
+	z.Add("a", 1.0)
+	z.Add("b", 2.0)
+	z.Add("c", 3.0)
+
+	tests := []struct {
+		name          string
+		ele           string
+		reverse       bool
+		expectedRank  int64
+		expectedScore float64
+		expectedOK    bool
+	}{
+		{"element exists - forward rank", "b", false, 1, 2.0, true},
+		{"element exists - reverse rank", "b", true, 1, 2.0, true},
+		{"lowest - forward rank", "a", false, 0, 1.0, true},
+		{"lowest - reverse rank", "a", true, 2, 1.0, true},
+		{"element not exists", "d", false, -1, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rank, score, ok := z.RankWithScore(tt.ele, tt.reverse)
+			assert.Equal(t, tt.expectedRank, rank)
+			assert.Equal(t, tt.expectedScore, score)
+			assert.Equal(t, tt.expectedOK, ok)
+		})
+	}
+
+	rank, _, ok := NewZSet().RankWithScore("a", false)
+	assert.Equal(t, int64(-1), rank)
+	assert.False(t, ok)
+}
+
+func TestZSet_RevRangeByScore(t *testing.T) {
+	z := NewZSet()
+	z.Add("a", 1)
+	z.Add("b", 2)
+	z.Add("c", 2)
+	z.Add("d", 2)
+	z.Add("e", 3)
+	z.Add("f", 4)
+
+	reversed := func(min, max float64) []struct {
+		Member string
+		Score  float64
+	} {
+		asc := z.RangeByScore(min, max, 0, -1)
+		slices.Reverse(asc)
+		return asc
+	}
+
+	tests := []struct {
+		name     string
+		max      float64
+		min      float64
+		offset   int64
+		count    int64
+		expected []string
+	}{
+		{"full range", math.Inf(1), math.Inf(-1), 0, -1, []string{"f", "e", "d", "c", "b", "a"}},
+		{"inner range", 3, 2, 0, -1, []string{"e", "d", "c", "b"}},
+		{"max between scores", 2.5, 1.5, 0, -1, []string{"d", "c", "b"}},
+		{"offset within duplicates", 3, 1, 2, -1, []string{"c", "b", "a"}},
+		{"offset and count within duplicates", 2, 2, 1, 1, []string{"c"}},
+		{"count limit", 4, 1, 0, 2, []string{"f", "e"}},
+		{"offset past range", 2, 2, 5, -1, nil},
+		{"below all", 0, -1, 0, -1, nil},
+		{"inverted", 1, 4, 0, -1, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := z.RevRangeByScore(tt.max, tt.min, tt.offset, tt.count)
+			var members []string
+			for _, r := range result {
+				members = append(members, r.Member)
+			}
+			assert.Equal(t, tt.expected, members)
+
+			if tt.offset == 0 && tt.count < 0 && tt.min <= tt.max {
+				assert.Equal(t, reversed(tt.min, tt.max), result)
+			}
+		})
+	}
+}
+
+func TestZSet_RejectNaN(t *testing.T) {
+	z := NewZSet()
+	z.Add("a", 1)
+	z.Add("b", 2)
+	z.Add("c", 3)
+
+	assert.False(t, z.Add("x", math.NaN()))
+	assert.False(t, z.Add("b", math.NaN()))
+	assert.True(t, math.IsNaN(z.IncrBy("a", math.NaN())))
+	z.Add("inf", math.Inf(1))
+	assert.True(t, math.IsNaN(z.IncrBy("inf", math.Inf(-1))))
+	z.Remove("inf")
+	added, updated, changed := z.AddOpt("y", math.NaN(), AddOptions{})
+	assert.False(t, added)
+	assert.False(t, updated)
+	assert.False(t, changed)
+	assert.Equal(t, 0, z.AddBatch(map[string]float64{"z": math.NaN()}))
+
+	_, exists := z.Score("x")
+	assert.False(t, exists)
+	score, _ := z.Score("a")
+	assert.Equal(t, float64(1), score)
+	score, _ = z.Score("b")
+	assert.Equal(t, float64(2), score)
+
+	assert.Equal(t, uint64(3), z.Len())
+	assert.Equal(t, int64(0), z.Rank("a", false))
+	assert.Equal(t, int64(1), z.Rank("b", false))
+	assert.Equal(t, int64(2), z.Rank("c", false))
+	member, _, ok := z.GetByRank(2, false)
+	assert.True(t, ok)
+	assert.Equal(t, "c", member)
+	assert.Equal(t, []string{"a", "b", "c"}, z.Members(false))
+}
+
+func TestZSet_GetByRankStress(t *testing.T) {
+	z := NewZSet()
+	const n = 5000
+	for i := 0; i < n; i++ {
+		z.Add(strconv.Itoa(i), float64(i%97))
+	}
+	// 反复更新分数和删除再插入，制造各种跨度组合
+	for round := 0; round < 3; round++ {
+		for i := 0; i < n; i += 3 {
+			z.IncrBy(strconv.Itoa(i), float64(round*7-i%13))
+		}
+		for i := 1; i < n; i += 5 {
+			z.Remove(strconv.Itoa(i))
+			z.Add(strconv.Itoa(i), float64((i*31)%101))
+		}
+	}
+
+	assert.Equal(t, uint64(n), z.Len())
+	var prevMember string
+	var prevScore float64
+	for i := int64(0); i < int64(z.Len()); i++ {
+		member, score, ok := z.GetByRank(i, false)
+		if !assert.True(t, ok, "rank %d", i) {
+			return
+		}
+		if i > 0 {
+			assert.True(t, prevScore < score || (prevScore == score && prevMember < member), "rank %d out of order", i)
+		}
+		assert.Equal(t, i, z.Rank(member, false))
+		prevMember, prevScore = member, score
+	}
+}
+
+func TestZSet_Contains(t *testing.T) {
+	z := NewZSet()
+	assert.False(t, z.Contains("a"))
+
+	z.Add("a", 1)
+	assert.True(t, z.Contains("a"))
+	assert.False(t, z.Contains("b"))
+
+	z.Remove("a")
+	assert.False(t, z.Contains("a"))
+}
+
+func TestNewZSetWithSource(t *testing.T) {
+	levels := func(z *ZSet[string]) []int {
+		var result []int
+		for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+			result = append(result, len(x.level))
+		}
+		return result
+	}
+	build := func(seed int64) *ZSet[string] {
+		z := NewZSetWithSource(rand.NewSource(seed))
+		for i := 0; i < 1000; i++ {
+			z.Add(strconv.Itoa(i), float64(i%17))
+		}
+		return z
+	}
+
+	a, b := build(42), build(42)
+	assert.Equal(t, levels(a), levels(b))
+	assert.Equal(t, a.zsl.level, b.zsl.level)
+	assert.Equal(t, a.RangeByRank(0, -1, false), b.RangeByRank(0, -1, false))
+
+	// 重建后仍使用注入的随机数源
+	assert.NoError(t, a.RebuildWithMaxLevel(16))
+	assert.NoError(t, b.RebuildWithMaxLevel(16))
+	assert.Equal(t, levels(a), levels(b))
+}
+
+func TestNewZSetWithConfig(t *testing.T) {
+	t.Run("invalid config", func(t *testing.T) {
+		for _, p := range []float64{0, 1, -0.5, 1.5, math.NaN()} {
+			z, err := NewZSetWithConfig(p, 16)
+			assert.Nil(t, z)
+			assert.ErrorIs(t, err, ErrInvalidProbability)
+		}
+		for _, maxLevel := range []int{0, -1, 65} {
+			z, err := NewZSetWithConfig(0.25, maxLevel)
+			assert.Nil(t, z)
+			assert.ErrorIs(t, err, ErrInvalidMaxLevel)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		p        float64
+		maxLevel int
+	}{
+		{"single level", 0.5, 1},
+		{"low max level", 0.9, 2},
+		{"default", SKIPLIST_P, SKIPLIST_MAXLEVEL},
+		{"sparse", 0.1, 48},
+		{"dense", 0.75, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z, err := NewZSetWithConfig(tt.p, tt.maxLevel)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.p, z.zsl.p)
+			assert.Equal(t, tt.maxLevel, z.zsl.maxLevel)
+
+			for i := 0; i < 2000; i++ {
+				assert.LessOrEqual(t, z.zsl.randomLevel(), tt.maxLevel)
+			}
+
+			for i := 0; i < 1000; i++ {
+				z.Add(strconv.Itoa(i), float64(i%37))
+			}
+			for i := 0; i < 1000; i += 3 {
+				z.Remove(strconv.Itoa(i))
+			}
+			assert.NoError(t, z.Validate())
+			assert.Equal(t, uint64(666), z.Len())
+			_, level, _ := z.Stats()
+			assert.LessOrEqual(t, level, tt.maxLevel)
+			for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+				assert.LessOrEqual(t, len(x.level), tt.maxLevel)
+			}
+
+			members := z.Members(false)
+			for i := 1; i < len(members); i++ {
+				prev, _ := z.Score(members[i-1])
+				cur, _ := z.Score(members[i])
+				assert.True(t, prev < cur || (prev == cur && members[i-1] < members[i]))
+			}
+
+			// 克隆和清空后沿用原有的层级参数
+			c := z.Clone()
+			assert.Equal(t, tt.p, c.zsl.p)
+			assert.Equal(t, tt.maxLevel, c.zsl.maxLevel)
+			assert.Equal(t, members, c.Members(false))
+
+			z.Clear()
+			assert.Equal(t, tt.p, z.zsl.p)
+			assert.Equal(t, tt.maxLevel, z.zsl.maxLevel)
+			z.ReplaceAll(map[string]float64{"a": 1, "b": 2})
+			assert.Equal(t, tt.p, z.zsl.p)
+			assert.Equal(t, []string{"a", "b"}, z.Members(false))
+			assert.NoError(t, z.Validate())
+		})
+	}
+}
+
+// countingSource 记录 Int63 的调用次数，用于确认随机数来自哪个随机数源。
+type countingSource struct {
+	rand.Source
+	calls int
+}
+
+func (s *countingSource) Int63() int64 {
+	s.calls++
+	return s.Source.Int63()
+}
+
+func TestZSet_GlobalRandUntouched(t *testing.T) {
+	// 用计数的随机数源替换包级 rng，测试结束后恢复
+	shared := &countingSource{Source: rand.NewSource(7)}
+	rngMu.Lock()
+	saved := rng
+	rng = rand.New(shared)
+	rngMu.Unlock()
+	defer func() {
+		rngMu.Lock()
+		rng = saved
+		rngMu.Unlock()
+	}()
+
+	// 层级只从集合自己的随机数源生成，随机查询只使用包级 rng，均不涉及 math/rand 的全局状态
+	own := &countingSource{Source: rand.NewSource(7)}
+	z := NewZSetWithSource(own)
+	for i := 0; i < 1000; i++ {
+		z.Add(strconv.Itoa(i), float64(i))
+	}
+	assert.Greater(t, own.calls, 0)
+	assert.Equal(t, 0, shared.calls)
+
+	levelCalls := own.calls
+	z.RandomMember()
+	z.RandomMembers(-10, true)
+	assert.Equal(t, levelCalls, own.calls)
+	assert.Greater(t, shared.calls, 0)
+
+	// 未指定随机数源的集合使用包级 rng 生成层级
+	before := shared.calls
+	NewZSet().Add("a", 1)
+	assert.Greater(t, shared.calls, before)
+}
+
+func TestZSet_RangeByRankMembers(t *testing.T) {
+	z := NewZSet()
+	z.Add("a", 1)
+	z.Add("b", 2)
+	z.Add("c", 3)
+	z.Add("d", 3)
+
+	tests := []struct {
+		name    string
+		start   int64
+		stop    int64
+		reverse bool
+	}{
+		{"all", 0, -1, false},
+		{"all reverse", 0, -1, true},
+		{"middle", 1, 2, false},
+		{"negative", -2, -1, true},
+		{"out of range", 5, 10, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var expected []string
+			for _, r := range z.RangeByRank(tt.start, tt.stop, tt.reverse) {
+				expected = append(expected, r.Member)
+			}
+			assert.Equal(t, expected, z.RangeByRankMembers(tt.start, tt.stop, tt.reverse))
+		})
+	}
+
+	assert.Equal(t, []string{"d", "c"}, z.RangeByRankMembers(0, 1, true))
+}
+
+func TestZSet_PopMinMaxN(t *testing.T) {
+	type entry = struct {
+		Member string
+		Score  float64
+	}
+	build := func() *ZSet[string] {
+		z := NewZSet()
+		z.Add("a", 1)
+		z.Add("b", 2)
+		z.Add("c", 3)
+		z.Add("d", 4)
+		return z
+	}
+
+	t.Run("min order", func(t *testing.T) {
+		z := build()
+		assert.Equal(t, []entry{{"a", 1}, {"b", 2}}, z.PopMinN(2))
+		assert.Equal(t, uint64(2), z.Len())
+		assert.False(t, z.Contains("a"))
+		assert.Equal(t, int64(0), z.Rank("c", false))
+	})
+
+	t.Run("max order", func(t *testing.T) {
+		z := build()
+		assert.Equal(t, []entry{{"d", 4}, {"c", 3}}, z.PopMaxN(2))
+		assert.Equal(t, []string{"a", "b"}, z.Members(false))
+	})
+
+	t.Run("n larger than set", func(t *testing.T) {
+		z := build()
+		assert.Equal(t, []entry{{"a", 1}, {"b", 2}, {"c", 3}, {"d", 4}}, z.PopMinN(10))
+		assert.Equal(t, uint64(0), z.Len())
+		assert.Empty(t, z.dict)
+		assert.Empty(t, z.PopMaxN(1))
+	})
+
+	t.Run("n zero", func(t *testing.T) {
+		z := build()
+		assert.Empty(t, z.PopMinN(0))
+		assert.Empty(t, z.PopMaxN(-1))
+		assert.Equal(t, uint64(4), z.Len())
+	})
+}
+
+func TestZSet_Scan(t *testing.T) {
+	z := NewZSet()
+	for i := 0; i < 25; i++ {
+		z.Add(strconv.Itoa(i), float64(i))
+	}
+
+	t.Run("full iteration", func(t *testing.T) {
+		var members []string
+		var cursor uint64
+		calls := 0
+		for {
+			next, items := z.Scan(cursor, 7)
+			calls++
+			for _, item := range items {
+				members = append(members, item.Member)
+			}
+			if next == 0 {
+				break
+			}
+			cursor = next
+		}
+		assert.Equal(t, 4, calls)
+		assert.Equal(t, z.Members(false), members)
+	})
+
+	t.Run("mid-iteration cursor", func(t *testing.T) {
+		next, items := z.Scan(10, 5)
+		assert.Equal(t, uint64(15), next)
+		assert.Len(t, items, 5)
+		assert.Equal(t, float64(10), items[0].Score)
+		assert.Equal(t, float64(14), items[4].Score)
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		next, items := z.Scan(20, 5)
+		assert.Equal(t, uint64(0), next)
+		assert.Len(t, items, 5)
+	})
+
+	t.Run("default count", func(t *testing.T) {
+		next, items := z.Scan(0, 0)
+		assert.Equal(t, uint64(10), next)
+		assert.Len(t, items, 10)
+	})
+
+	t.Run("huge count", func(t *testing.T) {
+		next, items := z.Scan(2, math.MaxInt64)
+		assert.Equal(t, uint64(0), next)
+		assert.Len(t, items, 23)
+		assert.Equal(t, float64(2), items[0].Score)
+
+		next, items = z.Scan(0, math.MaxInt64)
+		assert.Equal(t, uint64(0), next)
+		assert.Equal(t, z.Members(false)[24], items[24].Member)
+	})
+
+	t.Run("cursor past end", func(t *testing.T) {
+		next, items := z.Scan(100, 5)
+		assert.Equal(t, uint64(0), next)
+		assert.Empty(t, items)
+	})
+
+	t.Run("empty set", func(t *testing.T) {
+		next, items := NewZSet().Scan(0, 5)
+		assert.Equal(t, uint64(0), next)
+		assert.Empty(t, items)
+	})
+}
+
+func TestZSet_UpdateScore(t *testing.T) {
+	z := NewZSet()
+	z.Add("a", 1)
+	z.Add("b", 2)
+	z.Add("c", 3)
+
+	assert.ErrorIs(t, z.UpdateScore("missing", 5), ErrMemberNotFound)
+	assert.False(t, z.Contains("missing"))
+	assert.Equal(t, uint64(3), z.Len())
+
+	assert.NoError(t, z.UpdateScore("a", 10))
+	score, _ := z.Score("a")
+	assert.Equal(t, float64(10), score)
+	assert.Equal(t, int64(2), z.Rank("a", false))
+	assert.Equal(t, int64(0), z.Rank("b", false))
+	assert.Equal(t, []string{"b", "c", "a"}, z.Members(false))
+	assert.Equal(t, uint64(3), z.Len())
+}
+
+func TestZSet_ErrorVariants(t *testing.T) {
+	z := NewZSet()
+
+	_, err := z.ScoreE("a")
+	assert.ErrorIs(t, err, ErrMemberNotFound)
+	_, err = z.RankE("a", false)
+	assert.ErrorIs(t, err, ErrMemberNotFound)
+	_, _, err = z.GetByRankE(0, false)
+	assert.ErrorIs(t, err, ErrEmptySet)
+
+	z.Add("a", 1)
+	z.Add("b", 2)
+
+	score, err := z.ScoreE("b")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), score)
+
+	rank, err := z.RankE("b", true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), rank)
+	rank, err = z.RankE("missing", false)
+	assert.ErrorIs(t, err, ErrMemberNotFound)
+	assert.Equal(t, int64(-1), rank)
+
+	member, score, err := z.GetByRankE(1, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", member)
+	assert.Equal(t, float64(1), score)
+	_, _, err = z.GetByRankE(2, false)
+	assert.ErrorIs(t, err, ErrRankOutOfRange)
+	member, _, err = z.GetByRankE(-1, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", member)
+	_, _, err = z.GetByRankE(-3, false)
+	assert.ErrorIs(t, err, ErrRankOutOfRange)
+
+	assert.ErrorIs(t, z.UpdateScore("a", math.NaN()), ErrInvalidScore)
+	score, _ = z.Score("a")
+	assert.Equal(t, float64(1), score)
+}
+
+func TestZSet_ToMap(t *testing.T) {
+	assert.Empty(t, NewZSet().ToMap())
+
+	input := map[string]float64{"a": 1, "b": 2, "c": 3}
+	z := NewZSet()
+	z.AddBatch(input)
+
+	m := z.ToMap()
+	assert.Equal(t, input, m)
+
+	m["a"] = 100
+	m["d"] = 4
+	delete(m, "b")
+
+	assert.Equal(t, input, z.dict)
+	assert.Equal(t, []string{"a", "b", "c"}, z.Members(false))
+}
+
+func TestNewZSetFromMap(t *testing.T) {
+	m := map[string]float64{"c": 3, "a": 1, "b": 1, "d": -2}
+	z := NewZSetFromMap(m)
+
+	expected := NewZSet()
+	for _, ele := range []string{"a", "b", "c", "d"} {
+		expected.Add(ele, m[ele])
+	}
+
+	assert.Equal(t, uint64(len(m)), z.Len())
+	assert.Equal(t, m, z.ToMap())
+	assert.Equal(t, expected.RangeByRank(0, -1, false), z.RangeByRank(0, -1, false))
+
+	assert.Equal(t, uint64(0), NewZSetFromMap(nil).Len())
+}
+
+func TestZSet_RangeByScorePaged(t *testing.T) {
+	z := NewZSet()
+	for i := 0; i < 20; i++ {
+		z.Add(strconv.Itoa(i), float64(i/2))
+	}
+
+	tests := []struct {
+		name   string
+		min    float64
+		max    float64
+		offset int64
+		count  int64
+	}{
+		{"first page", 2, 6, 0, 3},
+		{"middle page", 2, 6, 3, 3},
+		{"last partial page", 2, 6, 9, 3},
+		{"offset past range", 2, 6, 20, 3},
+		{"unlimited count", 2, 6, 4, -1},
+		{"zero count", 2, 6, 0, 0},
+		{"whole set", math.Inf(-1), math.Inf(1), 5, 5},
+		{"empty range", 2.2, 2.8, 0, 5},
+		{"inverted", 6, 2, 0, 5},
+		{"nan min", math.NaN(), 6, 0, 5},
+		{"nan max", 2, math.NaN(), 0, 5},
+		{"huge count", 2, 6, 1, math.MaxInt64},
+		{"huge offset and count", 2, 6, math.MaxInt64, math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, total := z.RangeByScorePaged(tt.min, tt.max, tt.offset, tt.count)
+			assert.GreaterOrEqual(t, total, int64(0))
+			assert.Equal(t, z.Count(tt.min, tt.max), total)
+			if tt.count == 0 {
+				assert.Empty(t, items)
+			} else {
+				assert.Equal(t, z.RangeByScore(tt.min, tt.max, tt.offset, tt.count), items)
+			}
+			if tt.count > 0 {
+				assert.LessOrEqual(t, int64(len(items)), tt.count)
+				if tt.offset < total {
+					assert.Equal(t, min(tt.count, total-tt.offset), int64(len(items)))
+				}
+			}
+		})
+	}
+}
+
+func TestZSet_RevRange(t *testing.T) {
+	z := NewZSet()
+	for i := 0; i < 6; i++ {
+		z.Add(strconv.Itoa(i), float64(i))
+	}
+
+	tests := []struct {
+		name     string
+		start    int64
+		stop     int64
+		expected []string
+	}{
+		{"top three", 0, 2, []string{"5", "4", "3"}},
+		{"all", 0, -1, []string{"5", "4", "3", "2", "1", "0"}},
+		{"negative bounds", -2, -1, []string{"1", "0"}},
+		{"mixed bounds", 1, -3, []string{"4", "3", "2"}},
+		{"stop clamped", 4, 100, []string{"1", "0"}},
+		{"start after stop", 3, 1, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := z.RevRange(tt.start, tt.stop)
+			var members []string
+			for _, r := range result {
+				members = append(members, r.Member)
+			}
+			assert.Equal(t, tt.expected, members)
+		})
+	}
+
+	forward := z.RangeByRank(0, -1, false)
+	slices.Reverse(forward)
+	assert.Equal(t, forward, z.RevRange(0, -1))
+}
+
+func TestZSet_RevRangeByRank(t *testing.T) {
+	z := NewZSet()
+	for i := 0; i < 20; i++ {
+		z.Add("m"+strconv.Itoa(i), float64(i%7))
+	}
+	n := int64(z.Len())
+
+	// 完整的降序范围等于升序范围的逆序
+	forward := z.RangeByRank(0, n-1, false)
+	slices.Reverse(forward)
+	assert.Equal(t, forward, z.RevRangeByRank(0, n-1))
+	assert.Equal(t, z.RevRange(0, -1), z.RevRangeByRank(0, -1))
+
+	// 每个降序排名与逐个 GetByRank(rank, true) 的结果一致
+	rev := z.RevRangeByRank(0, -1)
+	for i := int64(0); i < n; i++ {
+		ele, score, ok := z.GetByRank(i, true)
+		assert.True(t, ok)
+		assert.Equal(t, rev[i].Member, ele)
+		assert.Equal(t, rev[i].Score, score)
+		assert.Equal(t, i, z.Rank(ele, true))
+	}
+
+	tests := []struct {
+		name        string
+		start, stop int64
+		expected    int
+	}{
+		{"head", 0, 2, 3},
+		{"negative", -3, -1, 3},
+		{"clamped stop", 15, 100, 5},
+		{"inverted", 5, 2, 0},
+		{"out of range", 20, 25, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := z.RevRangeByRank(tt.start, tt.stop)
+			assert.Len(t, items, tt.expected)
+			assert.Equal(t, z.RangeByRank(tt.start, tt.stop, true), items)
+		})
+	}
+}
+
+func TestZSet_RankByScore(t *testing.T) {
+	assert.Equal(t, int64(0), NewZSet().RankByScore(1))
+
+	z := NewZSet()
+	z.Add("a", 1)
+	z.Add("b", 2)
+	z.Add("c", 2)
+	z.Add("d", 2)
+	z.Add("e", 5)
+
+	tests := []struct {
+		name     string
+		score    float64
+		expected int64
+	}{
+		{"below all", 0, 0},
+		{"equal to min", 1, 0},
+		{"between", 1.5, 1},
+		{"duplicates", 2, 1},
+		{"after duplicates", 3, 4},
+		{"equal to max", 5, 4},
+		{"above all", 10, 5},
+		{"infinity", math.Inf(1), 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, z.RankByScore(tt.score))
+		})
+	}
+}
+
+func TestZSet_Quantile(t *testing.T) {
+	_, ok := NewZSet().Quantile(0.5)
+	assert.False(t, ok)
+
+	odd := NewZSet()
+	for i, score := range []float64{10, 20, 30, 40, 50} {
+		odd.Add(strconv.Itoa(i), score)
+	}
+	even := NewZSet()
+	for i, score := range []float64{10, 20, 30, 40} {
+		even.Add(strconv.Itoa(i), score)
+	}
+
+	tests := []struct {
+		name     string
+		z        *ZSet[string]
+		q        float64
+		expected float64
+	}{
+		{"min", odd, 0, 10},
+		{"max", odd, 1, 50},
+		{"median odd", odd, 0.5, 30},
+		{"median even", even, 0.5, 30},
+		{"p90", odd, 0.9, 50},
+		{"p25", odd, 0.25, 20},
+		{"below range clamped", odd, -1, 10},
+		{"above range clamped", odd, 2, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, ok := tt.z.Quantile(tt.q)
+			assert.True(t, ok)
+			assert.Equal(t, tt.expected, score)
+		})
+	}
+
+	_, ok = odd.Quantile(math.NaN())
+	assert.False(t, ok)
+}
+
+func BenchmarkZSet_Churn(b *testing.B) {
+	const n = 10000
+	z := newBenchZSet(n)
+	names := make([]string, n)
+	for i := range names {
+		names[i] = "member" + strconv.Itoa(i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ele := names[i%n]
+		z.Remove(ele)
+		z.Add(ele, float64(i))
+	}
+}
+
+func TestZSet_ChurnAllocs(t *testing.T) {
+	z := NewZSet()
+	names := make([]string, 1024)
+	for i := range names {
+		names[i] = strconv.Itoa(i)
+		z.Add(names[i], float64(i))
+	}
+
+	// 查找路径使用栈上的定长数组，删除的节点进入空闲链表，删除再添加不应产生堆分配
+	i := 0
+	assert.Equal(t, float64(0), testing.AllocsPerRun(1000, func() {
+		ele := names[i%len(names)]
+		z.Remove(ele)
+		z.Add(ele, float64(i))
+		z.IncrBy(ele, -1)
+		i++
+	}))
+	assert.NoError(t, z.Validate())
+}
+
+func TestZSet_NodeReuse(t *testing.T) {
+	z := NewZSet()
+	for i := 0; i < 2000; i++ {
+		z.Add(strconv.Itoa(i), float64(i))
+	}
+	z.RemoveRangeByRank(0, 499)
+	z.RemoveRangeByScore(1000, 1499)
+	for i := 1500; i < 1700; i++ {
+		z.Remove(strconv.Itoa(i))
+	}
+
+	// 空闲节点不能持有任何旧引用
+	pooled := 0
+	for level, nodes := range z.zsl.free {
+		assert.LessOrEqual(t, len(nodes), nodeFreeListMax)
+		for _, x := range nodes {
+			pooled++
+			assert.Len(t, x.level, level+1)
+			assert.Equal(t, "", x.ele)
+			assert.Equal(t, float64(0), x.score)
+			assert.Nil(t, x.backward)
+			for _, l := range x.level {
+				assert.Nil(t, l.forward)
+				assert.Equal(t, uint64(0), l.span)
+			}
+		}
+	}
+	assert.Greater(t, pooled, 0)
+
+	// 复用节点后结构仍然正确
+	for i := 0; i < 1200; i++ {
+		z.Add("new"+strconv.Itoa(i), float64(i%37))
+		z.IncrBy(strconv.Itoa(500+i%500), 1)
+	}
+	assert.NoError(t, z.Validate())
+	assert.Equal(t, uint64(2000-1200+1200), z.Len())
+	for i := int64(0); i < int64(z.Len()); i++ {
+		member, _, ok := z.GetByRank(i, false)
+		assert.True(t, ok)
+		assert.Equal(t, i, z.Rank(member, false))
+	}
+}
+
+func BenchmarkZSet_Insert(b *testing.B) {
+	names := make([]string, b.N)
+	for i := range names {
+		names[i] = "member" + strconv.Itoa(i)
+	}
+	z := NewZSet()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.Add(names[i], float64(i%1000))
+	}
+}
+
+func TestZSet_LargeSetNodeLevels(t *testing.T) {
+	z := NewZSetWithSource(rand.NewSource(3))
+	const n = 50000
+	for i := 0; i < n; i++ {
+		z.Add(strconv.Itoa(i), float64(i%1000))
+	}
+	assert.NoError(t, z.Validate())
+
+	// 各级节点的层级数组容量必须等于层级，覆盖所有分级
+	seen := make(map[int]bool)
+	for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		assert.Equal(t, len(x.level), cap(x.level))
+		seen[len(x.level)] = true
+	}
+	for level := 1; level <= 5; level++ {
+		assert.True(t, seen[level], "no node with level %d", level)
+	}
+
+	for i := 0; i < n; i += 2 {
+		z.Remove(strconv.Itoa(i))
+	}
+	assert.NoError(t, z.Validate())
+	assert.Equal(t, uint64(n/2), z.Len())
+}
+
+func TestZSet_WalkRange(t *testing.T) {
+	z := NewZSet()
+	for i := 0; i < 6; i++ {
+		z.Add(strconv.Itoa(i), float64(i))
+	}
+
+	type visit struct {
+		member string
+		rank   int64
+	}
+	walk := func(start, stop int64, reverse bool, limit int) []visit {
+		var visits []visit
+		z.WalkRange(start, stop, reverse, func(member string, score float64, rank int64) bool {
+			assert.Equal(t, float64(rank), score)
+			visits = append(visits, visit{member, rank})
+			return len(visits) < limit
+		})
+		return visits
+	}
+
+	t.Run("forward", func(t *testing.T) {
+		assert.Equal(t, []visit{{"1", 1}, {"2", 2}, {"3", 3}}, walk(1, 3, false, 100))
+	})
+
+	t.Run("reverse yields descending ranks", func(t *testing.T) {
+		assert.Equal(t, []visit{{"5", 5}, {"4", 4}, {"3", 3}}, walk(0, 2, true, 100))
+	})
+
+	t.Run("negative indices", func(t *testing.T) {
+		assert.Equal(t, []visit{{"4", 4}, {"5", 5}}, walk(-2, -1, false, 100))
+	})
+
+	t.Run("early stop", func(t *testing.T) {
+		assert.Equal(t, []visit{{"0", 0}, {"1", 1}}, walk(0, -1, false, 2))
+	})
+
+	t.Run("empty range", func(t *testing.T) {
+		assert.Empty(t, walk(4, 2, false, 100))
+		assert.Empty(t, walk(10, 20, false, 100))
+	})
+}
+
+func TestZSet_RangeByScoreFunc(t *testing.T) {
+	z := NewZSet()
+	for i := 0; i < 10; i++ {
+		z.Add("m"+strconv.Itoa(i), float64(i%5))
+	}
+
+	tests := []struct {
+		name     string
+		min, max float64
+	}{
+		{"all", math.Inf(-1), math.Inf(1)},
+		{"inner window", 1, 3},
+		{"single score", 2, 2},
+		{"between scores", 1.2, 1.8},
+		{"beyond all", 10, 20},
+		{"inverted", 3, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var visited []struct {
+				Member string
+				Score  float64
+			}
+			z.RangeByScoreFunc(tt.min, tt.max, func(member string, score float64) bool {
+				visited = append(visited, struct {
+					Member string
+					Score  float64
+				}{member, score})
+				return true
+			})
+			assert.Equal(t, z.RangeByScore(tt.min, tt.max, 0, -1), visited)
+		})
+	}
+
+	t.Run("early stop", func(t *testing.T) {
+		var visited []string
+		z.RangeByScoreFunc(1, 4, func(member string, _ float64) bool {
+			visited = append(visited, member)
+			return len(visited) < 3
+		})
+		assert.Equal(t, []string{"m1", "m6", "m2"}, visited)
+	})
+}
+
+func TestNewZSetWithComparator(t *testing.T) {
+	numeric := func(a, b string) bool {
+		x, _ := strconv.Atoi(a)
+		y, _ := strconv.Atoi(b)
+		return x < y
+	}
+
+	z := NewZSetWithComparator(numeric)
+	for _, id := range []string{"10", "9", "100", "1"} {
+		z.Add(id, 0)
+	}
+	assert.Equal(t, []string{"1", "9", "10", "100"}, z.Members(false))
+	assert.Equal(t, int64(1), z.Rank("9", false))
+	assert.Equal(t, int64(2), z.Rank("10", false))
+
+	assert.True(t, z.Remove("9"))
+	assert.Equal(t, int64(1), z.Rank("10", false))
+	assert.NoError(t, z.Validate())
+
+	lexical := NewZSetWithComparator(nil)
+	lexical.Add("10", 0)
+	lexical.Add("9", 0)
+	assert.Equal(t, []string{"10", "9"}, lexical.Members(false))
+}
+
+func TestZSet_IncrByChecked(t *testing.T) {
+	z := NewZSet()
+	z.Add("a", 1)
+	z.Add("big", math.MaxFloat64)
+	z.Add("small", -math.MaxFloat64)
+
+	score, err := z.IncrByChecked("a", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(3), score)
+
+	score, err = z.IncrByChecked("new", -5)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(-5), score)
+
+	tests := []struct {
+		name  string
+		ele   string
+		delta float64
+	}{
+		{"overflow to +Inf", "big", math.MaxFloat64},
+		{"overflow to -Inf", "small", -math.MaxFloat64},
+		{"infinite delta", "a", math.Inf(1)},
+		{"NaN delta", "a", math.NaN()},
+		{"missing member infinite delta", "missing", math.Inf(-1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, existed := z.Score(tt.ele)
+			score, err := z.IncrByChecked(tt.ele, tt.delta)
+			assert.ErrorIs(t, err, ErrInvalidScore)
+			assert.Equal(t, before, score)
+
+			after, exists := z.Score(tt.ele)
+			assert.Equal(t, existed, exists)
+			assert.Equal(t, before, after)
+			assert.NoError(t, z.Validate())
+		})
+	}
+
+	assert.Equal(t, []string{"small", "new", "a", "big"}, z.Members(false))
+}
+
+func TestZSet_MRank(t *testing.T) {
+	z := NewZSet()
+	z.Add("a", 1)
+	z.Add("b", 2)
+	z.Add("c", 3)
+
+	assert.Equal(t, []int64{2, -1, 0, 1}, z.MRank(false, "c", "missing", "a", "b"))
+	assert.Equal(t, []int64{0, -1, 2, 1}, z.MRank(true, "c", "missing", "a", "b"))
+	assert.Empty(t, z.MRank(false))
+	assert.Equal(t, []int64{-1}, NewZSet().MRank(false, "a"))
+}
+
+func TestZSet_RemoveAll(t *testing.T) {
+	z := newZSetOf(map[string]float64{"a": 1, "b": 2, "c": 3, "d": 4})
+
+	assert.Equal(t, 2, z.RemoveAll("b", "missing", "d", "b"))
+	assert.Equal(t, []string{"a", "c"}, z.Members(false))
+	assert.Equal(t, 0, z.RemoveAll("x", "y"))
+	assert.Equal(t, 0, z.RemoveAll())
+	assert.Equal(t, uint64(2), z.Len())
+	assert.NoError(t, z.Validate())
+}
+
+func TestZSet_SubtractSet(t *testing.T) {
+	tests := []struct {
+		name      string
+		z         map[string]float64
+		other     map[string]float64
+		removed   int
+		survivors []string
+	}{
+		{
+			name:      "partial overlap",
+			z:         map[string]float64{"a": 1, "b": 2, "c": 3, "d": 4},
+			other:     map[string]float64{"b": 100, "d": 0, "x": 5},
+			removed:   2,
+			survivors: []string{"a", "c"},
+		},
+		{
+			name:      "other larger",
+			z:         map[string]float64{"a": 1, "b": 2},
+			other:     map[string]float64{"b": 1, "c": 1, "d": 1, "e": 1},
+			removed:   1,
+			survivors: []string{"a"},
+		},
+		{
+			name:      "no overlap",
+			z:         map[string]float64{"a": 1, "b": 2},
+			other:     map[string]float64{"x": 1, "y": 2},
+			removed:   0,
+			survivors: []string{"a", "b"},
+		},
+		{
+			name:      "empty other",
+			z:         map[string]float64{"a": 1},
+			other:     map[string]float64{},
+			removed:   0,
+			survivors: []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := newZSetOf(tt.z)
+			other := newZSetOf(tt.other)
+			assert.Equal(t, tt.removed, z.SubtractSet(other))
+			assert.Equal(t, tt.survivors, z.Members(false))
+			assert.Equal(t, uint64(len(tt.other)), other.Len())
+			assert.NoError(t, z.Validate())
+		})
+	}
+
+	t.Run("self", func(t *testing.T) {
+		z := NewSyncZSet()
+		z.Add("a", 1)
+		z.Add("b", 2)
+		assert.Equal(t, 2, z.SubtractSet(z))
+		assert.Equal(t, uint64(0), z.Len())
+	})
+
+	t.Run("nil other", func(t *testing.T) {
+		z := newZSetOf(map[string]float64{"a": 1})
+		assert.Equal(t, 0, z.SubtractSet(nil))
+	})
+}
+
+func TestZSet_MergeAdd(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     map[string]float64
+		other    map[string]float64
+		expected map[string]float64
+		order    []string
+	}{
+		{
+			name:     "overlapping members are summed",
+			base:     map[string]float64{"a": 1, "b": 2, "c": 3},
+			other:    map[string]float64{"a": 10, "c": -2.5},
+			expected: map[string]float64{"a": 11, "b": 2, "c": 0.5},
+			order:    []string{"c", "b", "a"},
+		},
+		{
+			name:     "disjoint members are copied",
+			base:     map[string]float64{"a": 1},
+			other:    map[string]float64{"x": 5, "y": -1},
+			expected: map[string]float64{"a": 1, "x": 5, "y": -1},
+			order:    []string{"y", "a", "x"},
+		},
+		{
+			name:     "empty other is a no-op",
+			base:     map[string]float64{"a": 1, "b": 2},
+			other:    map[string]float64{},
+			expected: map[string]float64{"a": 1, "b": 2},
+			order:    []string{"a", "b"},
+		},
+		{
+			name:     "nan sums are skipped",
+			base:     map[string]float64{"a": math.Inf(1)},
+			other:    map[string]float64{"a": math.Inf(-1), "b": 1},
+			expected: map[string]float64{"a": math.Inf(1), "b": 1},
+			order:    []string{"b", "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := NewZSetFromMap(tt.base)
+			other := NewZSetFromMap(tt.other)
+			z.MergeAdd(other)
+			assert.Equal(t, tt.expected, z.ToMap())
+			assert.Equal(t, tt.order, z.Members(false))
+			assert.Equal(t, tt.other, other.ToMap())
+			assert.NoError(t, z.Validate())
+		})
+	}
+
+	t.Run("matches sum union", func(t *testing.T) {
+		a := NewZSetFromMap(map[string]float64{"a": 1, "b": 2, "c": 3})
+		b := NewZSetFromMap(map[string]float64{"b": 5, "d": 4})
+		expected := Union(a, b, [2]float64{1, 1}, AggregateSum)
+		a.MergeAdd(b)
+		assert.True(t, expected.Equal(a))
+	})
+
+	t.Run("merge into itself doubles scores", func(t *testing.T) {
+		z := NewZSetFromMap(map[string]float64{"a": 1, "b": 2})
+		z.MergeAdd(z)
+		assert.Equal(t, map[string]float64{"a": 2, "b": 4}, z.ToMap())
+		assert.NoError(t, z.Validate())
+
+		z.MergeAdd(nil)
+		assert.Equal(t, uint64(2), z.Len())
+	})
+}
+
+func TestZSet_MoveTo(t *testing.T) {
+	setup := func() (*ZSet[string], *ZSet[string]) {
+		src, dst := NewZSet(), NewZSet()
+		src.Add("a", 1)
+		src.Add("b", 2)
+		dst.Add("b", 10)
+		dst.Add("c", 3)
+		return src, dst
+	}
+
+	tests := []struct {
+		name     string
+		ele      string
+		moved    bool
+		srcLeft  []string
+		dstAfter map[string]float64
+	}{
+		{"present member", "a", true, []string{"b"}, map[string]float64{"a": 1, "b": 10, "c": 3}},
+		{"overwrites destination score", "b", true, []string{"a"}, map[string]float64{"b": 2, "c": 3}},
+		{"absent member", "x", false, []string{"a", "b"}, map[string]float64{"b": 10, "c": 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, dst := setup()
+			assert.Equal(t, tt.moved, src.MoveTo(dst, tt.ele))
+			assert.Equal(t, tt.srcLeft, src.Members(false))
+			assert.Equal(t, tt.dstAfter, dst.ToMap())
+			assert.NoError(t, src.Validate())
+			assert.NoError(t, dst.Validate())
+		})
+	}
+
+	t.Run("payload travels with member", func(t *testing.T) {
+		src, dst := NewZSetWithPayload(), NewZSetWithPayload()
+		src.AddP("a", 1, "pa")
+		dst.AddP("a", 5, "old")
+		assert.True(t, src.MoveTo(dst, "a"))
+		payload, _ := dst.Payload("a")
+		assert.Equal(t, "pa", payload)
+	})
+
+	t.Run("same set and nil destination", func(t *testing.T) {
+		src, _ := setup()
+		assert.True(t, src.MoveTo(src, "a"))
+		assert.False(t, src.MoveTo(src, "x"))
+		assert.False(t, src.MoveTo(nil, "a"))
+		assert.Equal(t, []string{"a", "b"}, src.Members(false))
+	})
+}
+
+func TestZSet_Rename(t *testing.T) {
+	setup := func() *ZSet[string] {
+		z := NewZSet()
+		z.Add("bob", 1)
+		z.Add("dave", 2)
+		z.Add("carol", 2)
+		z.Add("erin", 3)
+		return z
+	}
+
+	tests := []struct {
+		name     string
+		oldEle   string
+		newEle   string
+		renamed  bool
+		expected []string
+	}{
+		{"keeps rank", "bob", "alice", true, []string{"alice", "carol", "dave", "erin"}},
+		{"tie-break rank changes", "dave", "bill", true, []string{"bob", "bill", "carol", "erin"}},
+		{"missing source", "zoe", "amy", false, []string{"bob", "carol", "dave", "erin"}},
+		{"colliding destination", "bob", "erin", false, []string{"bob", "carol", "dave", "erin"}},
+		{"same name", "bob", "bob", true, []string{"bob", "carol", "dave", "erin"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := setup()
+			before := z.ToMap()
+
+			assert.Equal(t, tt.renamed, z.Rename(tt.oldEle, tt.newEle))
+			assert.Equal(t, tt.expected, z.Members(false))
+			if tt.renamed && tt.oldEle != tt.newEle {
+				assert.False(t, z.Contains(tt.oldEle))
+				score, _ := z.Score(tt.newEle)
+				assert.Equal(t, before[tt.oldEle], score)
+			} else {
+				assert.Equal(t, before, z.ToMap())
+			}
+			assert.NoError(t, z.Validate())
+		})
+	}
+
+	t.Run("payload travels with member", func(t *testing.T) {
+		z := NewZSetWithPayload()
+		z.AddP("bob", 1, "profile")
+		assert.True(t, z.Rename("bob", "robert"))
+		payload, _ := z.Payload("robert")
+		assert.Equal(t, "profile", payload)
+	})
+}
+
+func TestZSet_Filter(t *testing.T) {
+	type entry = struct {
+		Member string
+		Score  float64
+	}
+	z := newZSetOf(map[string]float64{
+		"spam1": -5, "alice": 10, "spam2": -1, "bob": 3, "spammer": 7,
+	})
+
+	negative := z.Filter(func(member string, score float64) bool { return score < 0 })
+	assert.Equal(t, []entry{{"spam1", -5}, {"spam2", -1}}, negative)
+
+	prefixed := z.Filter(func(member string, score float64) bool { return strings.HasPrefix(member, "spam") })
+	assert.Equal(t, []entry{{"spam1", -5}, {"spam2", -1}, {"spammer", 7}}, prefixed)
+
+	assert.Nil(t, z.Filter(func(string, float64) bool { return false }))
+	assert.Len(t, z.Filter(func(string, float64) bool { return true }), 5)
+}
+
+func TestZSet_Equal(t *testing.T) {
+	a := NewZSet()
+	for _, ele := range []string{"a", "b", "c", "d"} {
+		a.Add(ele, float64(len(ele)))
+	}
+	b := NewZSet()
+	for _, ele := range []string{"d", "c", "b", "a"} {
+		b.Add(ele, float64(len(ele)))
+	}
+	b.Add("e", 1)
+	b.Remove("e")
+
+	assert.True(t, a.Equal(b))
+	assert.True(t, b.Equal(a))
+	assert.True(t, a.Equal(a))
+	assert.True(t, NewZSet().Equal(NewZSet()))
+
+	b.Add("c", 2)
+	assert.False(t, a.Equal(b))
+
+	b.Add("c", 1)
+	b.Add("extra", 1)
+	assert.False(t, a.Equal(b))
+
+	assert.False(t, a.Equal(nil))
+	var nilSet *ZSet[string]
+	assert.True(t, nilSet.Equal(nil))
+}
+
+func TestZSet_String(t *testing.T) {
+	assert.Equal(t, "[]", NewZSet().String())
+
+	z := NewZSet()
+	z.Add("c", 3)
+	z.Add("a", 1)
+	z.Add("b", 2.5)
+	z.Add("d", math.Inf(1))
+	z.Add("e", -1e21)
+	assert.Equal(t, "[e:-1e+21 a:1.0 b:2.5 c:3.0 d:+Inf]", z.String())
+
+	ints := NewZSetFunc(func(a, b int) bool { return a < b })
+	ints.Add(7, 0)
+	assert.Equal(t, "[7:0.0]", ints.String())
+
+	large := NewZSet()
+	for i := 0; i < 250; i++ {
+		large.Add("m"+strconv.Itoa(i), float64(i))
+	}
+	s := large.String()
+	assert.True(t, strings.HasPrefix(s, "[m0:0.0 m1:1.0 "))
+	assert.True(t, strings.HasSuffix(s, " m99:99.0 ... (+150 more)]"))
+	assert.NotContains(t, s, "m100:")
+}
+
+func TestZSet_RangeByRankPresized(t *testing.T) {
+	z := NewZSet()
+	for i := 0; i < 10; i++ {
+		z.Add(strconv.Itoa(i), float64(i))
+	}
+
+	tests := []struct {
+		name     string
+		start    int64
+		stop     int64
+		expected int
+	}{
+		{"inside", 2, 5, 4},
+		{"stop clamped", 7, 100, 3},
+		{"start clamped", -100, 1, 2},
+		{"negative", -3, -1, 3},
+		{"empty", 5, 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := z.RangeByRank(tt.start, tt.stop, false)
+			assert.Len(t, result, tt.expected)
+			assert.Equal(t, tt.expected, cap(result))
+
+			members := z.RangeByRankMembers(tt.start, tt.stop, true)
+			assert.Len(t, members, tt.expected)
+			assert.Equal(t, tt.expected, cap(members))
+		})
+	}
+}
+
+func BenchmarkZSet_RangeByRank(b *testing.B) {
+	z := newBenchZSet(100000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.RangeByRank(1000, 1999, false)
+	}
+}